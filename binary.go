@@ -0,0 +1,824 @@
+package arkserde
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// binaryMagic identifies data in the binary format.
+const binaryMagic = "ARKB"
+
+// BinaryFormatVersion is the version of the binary format written by [SerializeBinary].
+const BinaryFormatVersion = 1
+
+// Encodings of component and resource data in the binary format.
+const (
+	encodingRaw  byte = 0 // Raw memory of a plain-old-data type.
+	encodingJSON byte = 1 // JSON, for types that contain pointers.
+)
+
+// Byte orders of the platform that wrote binary data.
+const (
+	orderLittleEndian byte = 0
+	orderBigEndian    byte = 1
+)
+
+// SerializeBinary serializes an Ark [ecs.World] to a compact binary format.
+//
+// Serializes the same data as [Serialize].
+// Components and resources of plain-old-data types, i.e. types without pointers, strings, slices or maps,
+// are stored as a copy of their memory, one column per archetype.
+// All other types fall back to JSON, and must be "JSON-able" like for [Serialize].
+//
+// The data contains a table of all types, with their size and a fingerprint of their memory layout.
+// [DeserializeBinary] fails if a type's layout does not match, instead of loading garbage.
+// As memory is copied as-is, the data can only be loaded on platforms with the same byte order.
+//
+// The output is deterministic. Supports all options except [Options.Columnar], which has no effect.
+func SerializeBinary(world *ecs.World, options ...Option) ([]byte, error) {
+	opts := newSerdeOptions(options...)
+
+	buffer := bytes.Buffer{}
+	if opts.compressed {
+		err := writeGZip(&buffer, opts.compressionLevel, func(w io.Writer) error {
+			return serializeBinary(w, world, &opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	if err := serializeBinary(&buffer, world, &opts); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// DeserializeBinary deserializes data written by [SerializeBinary] into an [ecs.World].
+//
+// Has the same requirements on the world as [Deserialize].
+// Additionally, all plain-old-data types must have the same memory layout as when the data was serialized.
+// Migrations (see [Options.Migrate]) can only be applied to types that were stored as JSON.
+// Like for [Deserialize], all data is decoded and checked before it is added to the world.
+// On error, the world is left unchanged, except for types registered from the global registry.
+func DeserializeBinary(data []byte, world *ecs.World, options ...Option) error {
+	opts := newSerdeOptions(options...)
+
+	if opts.compressed {
+		var err error
+		data, err = uncompressGZip(data)
+		if err != nil {
+			return newDecodeError("", -1, "", err)
+		}
+	}
+
+	return deserializeBinary(data, world, &opts)
+}
+
+func serializeBinary(w io.Writer, world *ecs.World, opts *serdeOptions) error {
+	writer := binaryWriter{bufio.NewWriterSize(w, writeBufferSize)}
+
+	writer.WriteString(binaryMagic)
+	writer.WriteByte(nativeOrder())
+	writer.uvarint(BinaryFormatVersion)
+	writer.uvarint(uint64(opts.schemaVersion))
+
+	if opts.skipEntities {
+		writer.WriteByte(0)
+	} else {
+		writer.WriteByte(1)
+		if err := serializeBinaryEntities(world, writer, opts); err != nil {
+			return err
+		}
+	}
+
+	if err := serializeBinaryResources(world, writer, opts); err != nil {
+		return err
+	}
+
+	return writer.Flush()
+}
+
+func serializeBinaryEntities(world *ecs.World, writer binaryWriter, opts *serdeOptions) error {
+	entities, selection := selectEntities(world, opts)
+
+	writer.uvarint(uint64(len(entities.Entities)))
+	buf := make([]byte, 0, 8)
+	for i := range entities.Entities {
+		buf, _ = entities.Entities[i].AppendBinary(buf[:0])
+		writer.Write(buf)
+	}
+	writer.uvarint(uint64(len(entities.Alive)))
+	for _, id := range entities.Alive {
+		writer.uvarint(uint64(id))
+	}
+	writer.uvarint(uint64(entities.Next))
+	writer.uvarint(uint64(entities.Available))
+
+	// Type table, in the order of component IDs.
+	names := componentNames(world, opts)
+	infos := componentInfos(world)
+	skipComponents := skipMask(world, opts)
+	uniqueNames := typeNames{}
+
+	typeIndex := make([]int, len(infos))
+	types := []ecs.ID{}
+	if !opts.skipAllComponents {
+		for _, id := range ecs.ComponentIDs(world) {
+			if skipComponents.Get(id) {
+				continue
+			}
+			info := infos[id.Index()]
+			if err := uniqueNames.add(names[id.Index()], info.Type, "component"); err != nil {
+				return err
+			}
+			typeIndex[id.Index()] = len(types)
+			types = append(types, id)
+		}
+	}
+	writer.uvarint(uint64(len(types)))
+	for _, id := range types {
+		info := infos[id.Index()]
+		writer.typeInfo(names[id.Index()], info.Type, info.IsRelation, isRaw(info.Type, opts))
+	}
+
+	// Archetypes, with one column per component.
+	groups := 0
+	err := forEachArchetype(world, infos, opts, selection, nil, func(group *archetypeGroup) error {
+		groups++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writer.uvarint(uint64(groups))
+
+	u := world.Unsafe()
+	column := []byte{}
+	return forEachArchetype(world, infos, opts, selection, nil, func(group *archetypeGroup) error {
+		writer.uvarint(uint64(len(group.indices)))
+		for _, idx := range group.indices {
+			writer.uvarint(uint64(idx))
+		}
+
+		writer.uvarint(uint64(len(group.ids)))
+		relIndex := 0
+		for _, id := range group.ids {
+			info := infos[id.Index()]
+			writer.uvarint(uint64(typeIndex[id.Index()]))
+			if info.IsRelation {
+				buf, _ = group.targets[relIndex].AppendBinary(buf[:0])
+				writer.Write(buf)
+				relIndex++
+			}
+
+			column = column[:0]
+			if isRaw(info.Type, opts) {
+				size := info.Type.Size()
+				for _, entity := range group.entities {
+					beforeSerialize(info.Type, u.Get(entity, id))
+					column = appendMemory(column, u.Get(entity, id), size)
+				}
+			} else {
+				column = append(column, '[')
+				for j, entity := range group.entities {
+					jsonData, err := encodeValue(info.Type, u.Get(entity, id), opts)
+					if err != nil {
+						return err
+					}
+					if j > 0 {
+						column = append(column, ',')
+					}
+					column = append(column, jsonData...)
+				}
+				column = append(column, ']')
+			}
+			writer.bytes(column)
+		}
+		return nil
+	})
+}
+
+func serializeBinaryResources(world *ecs.World, writer binaryWriter, opts *serdeOptions) error {
+	if opts.skipAllResources {
+		writer.uvarint(0)
+		return nil
+	}
+
+	resIDs := []ecs.ResID{}
+	resTypes := []reflect.Type{}
+	uniqueNames := typeNames{}
+	for _, id := range ecs.ResourceIDs(world) {
+		if tp, ok := ecs.ResourceType(world, id); ok && tp != unknownDataType {
+			if !opts.skipsResource(tp) {
+				if err := uniqueNames.add(typeName(tp, opts), tp, "resource"); err != nil {
+					return err
+				}
+				resIDs = append(resIDs, id)
+				resTypes = append(resTypes, tp)
+			}
+		}
+	}
+
+	writer.uvarint(uint64(len(resIDs)))
+	for i, id := range resIDs {
+		tp := resTypes[i]
+		ptr := reflect.ValueOf(world.Resources().Get(id)).UnsafePointer()
+
+		writer.typeInfo(typeName(tp, opts), tp, false, isRaw(tp, opts))
+		if isRaw(tp, opts) {
+			beforeSerialize(tp, ptr)
+			writer.bytes(appendMemory(nil, ptr, tp.Size()))
+			continue
+		}
+		jsonData, err := encodeValue(tp, ptr, opts)
+		if err != nil {
+			return err
+		}
+		writer.bytes(jsonData)
+	}
+	return nil
+}
+
+func deserializeBinary(data []byte, world *ecs.World, opts *serdeOptions) error {
+	reader := binaryReader{data: data}
+
+	if string(reader.next(len(binaryMagic))) != binaryMagic {
+		return newDecodeError("", -1, "", fmt.Errorf("data is not in the binary format"))
+	}
+	if order := reader.byte(); reader.err == nil && order != nativeOrder() {
+		return newDecodeError("Version", -1, "", fmt.Errorf("data was written on a platform with a different byte order"))
+	}
+	if format := reader.uvarint(); reader.err == nil && format > BinaryFormatVersion {
+		return newDecodeError("Version", -1, "", fmt.Errorf("unsupported binary format version %d, supports up to version %d", format, BinaryFormatVersion))
+	}
+	schema := int(reader.uvarint())
+	hasEntities := reader.byte() != 0
+	if reader.err != nil {
+		return newDecodeError("Version", -1, "", reader.err)
+	}
+	if err := checkVersion(&version{Format: FormatVersion, Schema: schema}, opts); err != nil {
+		return newDecodeError("Version", -1, "", err)
+	}
+
+	comps := &decodedComponents{}
+	if hasEntities && !opts.skipEntities {
+		var err error
+		if comps, err = decodeBinaryEntities(&reader, world, schema, opts); err != nil {
+			return err
+		}
+	} else if hasEntities {
+		if err := skipBinaryEntities(&reader); err != nil {
+			return err
+		}
+	}
+
+	resources, err := decodeBinaryResources(&reader, world, schema, opts)
+	if err != nil {
+		return err
+	}
+
+	comps.apply()
+	resources.apply(world, nil)
+
+	return afterLoad(world, comps.loader, resources.targets, opts)
+}
+
+// binaryType is an entry of the type table of binary data.
+type binaryType struct {
+	name        string
+	isRelation  bool
+	size        uint64
+	fingerprint uint64
+	encoding    byte
+}
+
+// readBinaryEntities reads the entity pool and the type table.
+func readBinaryEntities(reader *binaryReader) (ecs.EntityDump, []binaryType, error) {
+	dump := ecs.EntityDump{}
+
+	numEntities := reader.count(8)
+	dump.Entities = make([]ecs.Entity, numEntities)
+	for i := range dump.Entities {
+		if buf := reader.next(8); buf != nil {
+			_ = dump.Entities[i].UnmarshalBinary(buf)
+		}
+	}
+	numAlive := reader.count(1)
+	dump.Alive = make([]uint32, numAlive)
+	for i := range dump.Alive {
+		dump.Alive[i] = uint32(reader.uvarint())
+	}
+	dump.Next = uint32(reader.uvarint())
+	dump.Available = uint32(reader.uvarint())
+	if reader.err != nil {
+		return dump, nil, newDecodeError("World", -1, "", reader.err)
+	}
+
+	types := make([]binaryType, reader.count(1))
+	for i := range types {
+		types[i] = reader.typeInfo()
+	}
+	if reader.err != nil {
+		return dump, nil, newDecodeError("Types", -1, "", reader.err)
+	}
+	return dump, types, nil
+}
+
+func skipBinaryEntities(reader *binaryReader) error {
+	_, types, err := readBinaryEntities(reader)
+	if err != nil {
+		return err
+	}
+	numArchetypes := reader.count(1)
+	for a := range numArchetypes {
+		numEntities := reader.count(1)
+		for range numEntities {
+			reader.uvarint()
+		}
+		numColumns := reader.count(1)
+		for range numColumns {
+			typeIndex := reader.uvarint()
+			if reader.err != nil {
+				return newDecodeError("Archetypes", a, "", reader.err)
+			}
+			if typeIndex >= uint64(len(types)) {
+				return newDecodeError("Archetypes", a, "", fmt.Errorf("type index %d out of range for %d types", typeIndex, len(types)))
+			}
+			if types[typeIndex].isRelation {
+				reader.next(8)
+			}
+			reader.bytes()
+		}
+		if reader.err != nil {
+			return newDecodeError("Archetypes", a, "", reader.err)
+		}
+	}
+	if reader.err != nil {
+		return newDecodeError("Archetypes", -1, "", reader.err)
+	}
+	return nil
+}
+
+// decodeBinaryEntities decodes the entity pool and the archetypes, without adding them to the world.
+func decodeBinaryEntities(reader *binaryReader, world *ecs.World, schema int, opts *serdeOptions) (*decodedComponents, error) {
+	dump, types, err := readBinaryEntities(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(types))
+	for i := range types {
+		names[i] = types[i].name
+	}
+	if err := registerComponents(world, names, opts); err != nil {
+		return nil, err
+	}
+	loader, err := newEntityLoader(world, &dump, schema, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the type table before entities are loaded.
+	ids := make([]ecs.ID, len(types))
+	unknown := make([]bool, len(types))
+	for i, tp := range types {
+		id, ok := loader.ids[tp.name]
+		if !ok {
+			if err := checkBinaryUnknown("component", opts.componentPolicy(tp.name)); err != nil {
+				return nil, newDecodeError("Types", -1, tp.name, err)
+			}
+			unknown[i] = true
+			continue
+		}
+		ids[i] = id
+		if loader.skipComponents.Get(id) {
+			continue
+		}
+		if tp.isRelation != loader.infos[id.Index()].IsRelation {
+			return nil, newDecodeError("Types", -1, tp.name, fmt.Errorf("component type was stored with a different relation status"))
+		}
+		if err := checkBinaryType(&tp, loader.infos[id.Index()].Type, schema, opts); err != nil {
+			return nil, newDecodeError("Types", -1, tp.name, err)
+		}
+	}
+
+	decoded := decodedComponents{loader: loader}
+	numArchetypes := reader.count(1)
+	for a := range numArchetypes {
+		numEntities := reader.count(1)
+		arch := decodedArchetype{entities: make([]int, 0, numEntities)}
+		for range numEntities {
+			arch.entities = append(arch.entities, int(reader.uvarint()))
+		}
+		if reader.err != nil {
+			return nil, newDecodeError("Archetypes", a, "", reader.err)
+		}
+		if err := loader.markLoaded(arch.entities); err != nil {
+			return nil, newDecodeError("Archetypes", a, "", err)
+		}
+
+		numColumns := reader.count(1)
+		for range numColumns {
+			typeIndex := reader.uvarint()
+			if reader.err != nil {
+				return nil, newDecodeError("Archetypes", a, "", reader.err)
+			}
+			if typeIndex >= uint64(len(types)) {
+				return nil, newDecodeError("Archetypes", a, "", fmt.Errorf("type index %d out of range for %d types", typeIndex, len(types)))
+			}
+			id := ids[typeIndex]
+
+			target := ecs.Entity{}
+			if types[typeIndex].isRelation {
+				if buf := reader.next(8); buf != nil {
+					_ = target.UnmarshalBinary(buf)
+				}
+			}
+			column := reader.bytes()
+			if reader.err != nil {
+				return nil, newDecodeError("Archetypes", a, types[typeIndex].name, reader.err)
+			}
+			if unknown[typeIndex] || loader.skipComponents.Get(id) {
+				continue
+			}
+			if loader.infos[id.Index()].IsRelation {
+				if err := loader.checkTarget(target); err != nil {
+					return nil, newDecodeError("Archetypes", a, types[typeIndex].name, err)
+				}
+			}
+			values, err := decodeBinaryColumn(loader, id, len(arch.entities), column)
+			if err != nil {
+				return nil, newDecodeError("Archetypes", a, types[typeIndex].name, err)
+			}
+			arch.ids = append(arch.ids, id)
+			arch.targets = append(arch.targets, target)
+			arch.columns = append(arch.columns, values)
+		}
+		decoded.archetypes = append(decoded.archetypes, arch)
+	}
+	if reader.err != nil {
+		return nil, newDecodeError("Archetypes", -1, "", reader.err)
+	}
+
+	if err := loader.checkArchetypes(); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// decodeBinaryColumn decodes a column of components into a slice, for the given number of entities.
+func decodeBinaryColumn(loader *entityLoader, id ecs.ID, count int, column []byte) (reflect.Value, error) {
+	tp := loader.infos[id.Index()].Type
+
+	if isRaw(tp, loader.opts) {
+		size := int(tp.Size())
+		if len(column) != size*count {
+			return reflect.Value{}, fmt.Errorf("found %d bytes, expected %d", len(column), size*count)
+		}
+		values := reflect.MakeSlice(reflect.SliceOf(tp), count, count)
+		if count > 0 {
+			copyMemory(values.UnsafePointer(), column)
+		}
+		return values, nil
+	}
+
+	values, err := loader.decodeColumn(tp, column)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if values.Len() != count {
+		return reflect.Value{}, fmt.Errorf("found %d values, but archetype has %d entities", values.Len(), count)
+	}
+	return values, nil
+}
+
+// decodeBinaryResources decodes the resources, without assigning them to the world's resources.
+// Resources added from the global registry are removed again on error.
+func decodeBinaryResources(reader *binaryReader, world *ecs.World, schema int, opts *serdeOptions) (*decodedResources, error) {
+	numResources := reader.count(1)
+	if reader.err != nil {
+		return nil, newDecodeError("Resources", -1, "", reader.err)
+	}
+	if opts.skipAllResources {
+		return &decodedResources{}, nil
+	}
+
+	infos := make([]binaryType, numResources)
+	resources := make([][]byte, numResources)
+	names := make([]string, numResources)
+	for i := range numResources {
+		infos[i] = reader.typeInfo()
+		resources[i] = reader.bytes()
+		names[i] = infos[i].name
+	}
+	if reader.err != nil {
+		return nil, newDecodeError("Resources", -1, "", reader.err)
+	}
+
+	added, err := registerResources(world, names, opts)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeRegisteredBinaryResources(world, infos, resources, schema, opts)
+	if err != nil {
+		for _, id := range added {
+			world.Resources().Remove(id)
+		}
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func decodeRegisteredBinaryResources(world *ecs.World, infos []binaryType, resources [][]byte, schema int, opts *serdeOptions) (*decodedResources, error) {
+	loader, err := newResourceLoader(world, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded := decodedResources{}
+	for i := range infos {
+		tpInfo := infos[i]
+		data := resources[i]
+
+		if _, ok := loader.resIds[tpInfo.name]; !ok {
+			if err := checkBinaryUnknown("resource", opts.resourcePolicy(tpInfo.name)); err != nil {
+				return nil, newDecodeError("Resources", -1, tpInfo.name, err)
+			}
+			continue
+		}
+		target, tp, err := loader.resolve(tpInfo.name)
+		if err != nil {
+			return nil, newDecodeError("Resources", -1, tpInfo.name, err)
+		}
+		if tp == nil {
+			continue
+		}
+		if err := checkBinaryType(&tpInfo, tp, schema, opts); err != nil {
+			return nil, newDecodeError("Resources", -1, tpInfo.name, err)
+		}
+
+		var value reflect.Value
+		if tpInfo.encoding == encodingRaw {
+			if uint64(len(data)) != tpInfo.size {
+				return nil, newDecodeError("Resources", -1, tpInfo.name, fmt.Errorf("found %d bytes, expected %d", len(data), tpInfo.size))
+			}
+			value = reflect.New(tp)
+			copyMemory(value.UnsafePointer(), data)
+		} else if value, err = decodeComponent(tp, schema, data, opts); err != nil {
+			return nil, newDecodeError("Resources", -1, tpInfo.name, err)
+		}
+		decoded.targets = append(decoded.targets, target)
+		decoded.values = append(decoded.values, value)
+	}
+	return &decoded, nil
+}
+
+// checkBinaryUnknown checks whether an unknown type is allowed in binary data.
+// The binary format does not support preserving unknown types.
+func checkBinaryUnknown(kind string, policy UnknownPolicy) error {
+	switch policy {
+	case UnknownIgnore:
+		return nil
+	case UnknownPreserve:
+		return fmt.Errorf("%s type is not registered, and can't be preserved in the binary format", kind)
+	default:
+		return fmt.Errorf("%s type is not registered", kind)
+	}
+}
+
+// checkBinaryType checks that a type from the type table is compatible with the registered type.
+func checkBinaryType(info *binaryType, tp reflect.Type, schema int, opts *serdeOptions) error {
+	if isRaw(tp, opts) {
+		if info.encoding != encodingRaw {
+			return fmt.Errorf("type was stored as JSON, but is plain data")
+		}
+		if info.size != uint64(tp.Size()) || info.fingerprint != layoutFingerprint(tp) {
+			return fmt.Errorf("memory layout does not match the serialized data")
+		}
+		if _, ok := opts.migrations[tp]; ok && schema < opts.schemaVersion {
+			return fmt.Errorf("can't migrate type, as it is stored as raw memory")
+		}
+		return nil
+	}
+	if info.encoding != encodingJSON {
+		return fmt.Errorf("type was stored as raw memory, but is not plain data or has a codec")
+	}
+	return nil
+}
+
+// isRaw checks whether values of a type are stored as raw memory.
+// This is the case for plain data types without a codec.
+func isRaw(tp reflect.Type, opts *serdeOptions) bool {
+	_, hasCodec := opts.codecs[tp]
+	return !hasCodec && isPlainData(tp)
+}
+
+// isPlainData checks whether a type contains no pointers, strings, slices, maps, interfaces, etc.
+// Values of such types can be copied as raw memory.
+func isPlainData(tp reflect.Type) bool {
+	switch tp.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return isPlainData(tp.Elem())
+	case reflect.Struct:
+		for i := range tp.NumField() {
+			if !isPlainData(tp.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// layoutFingerprint returns a hash of the memory layout of a type.
+// It covers the kinds, sizes and offsets of all fields, as well as the field names,
+// but not the names of the types themselves.
+func layoutFingerprint(tp reflect.Type) uint64 {
+	hash := fnv.New64a()
+	buf := []byte{}
+	buf = appendLayout(buf, tp)
+	hash.Write(buf)
+	return hash.Sum64()
+}
+
+func appendLayout(buf []byte, tp reflect.Type) []byte {
+	buf = append(buf, tp.Kind().String()...)
+	buf = append(buf, ':')
+	buf = strconv.AppendUint(buf, uint64(tp.Size()), 10)
+	switch tp.Kind() {
+	case reflect.Array:
+		buf = append(buf, '[')
+		buf = strconv.AppendInt(buf, int64(tp.Len()), 10)
+		buf = append(buf, ']')
+		buf = appendLayout(buf, tp.Elem())
+	case reflect.Struct:
+		buf = append(buf, '{')
+		for i := range tp.NumField() {
+			field := tp.Field(i)
+			buf = append(buf, field.Name...)
+			buf = append(buf, '@')
+			buf = strconv.AppendUint(buf, uint64(field.Offset), 10)
+			buf = append(buf, ' ')
+			buf = appendLayout(buf, field.Type)
+			buf = append(buf, ';')
+		}
+		buf = append(buf, '}')
+	}
+	return buf
+}
+
+// nativeOrder returns the byte order of the current platform.
+func nativeOrder() byte {
+	value := uint16(1)
+	if *(*byte)(unsafe.Pointer(&value)) == 1 {
+		return orderLittleEndian
+	}
+	return orderBigEndian
+}
+
+// appendMemory appends size bytes of raw memory at ptr to buf.
+func appendMemory(buf []byte, ptr unsafe.Pointer, size uintptr) []byte {
+	if size == 0 {
+		return buf
+	}
+	return append(buf, unsafe.Slice((*byte)(ptr), size)...)
+}
+
+// copyMemory copies data to the raw memory at ptr.
+func copyMemory(ptr unsafe.Pointer, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	copy(unsafe.Slice((*byte)(ptr), len(data)), data)
+}
+
+// binaryWriter writes primitives of the binary format.
+// Errors are sticky in the underlying [bufio.Writer] and reported by Flush.
+type binaryWriter struct {
+	*bufio.Writer
+}
+
+func (w binaryWriter) uvarint(value uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], value)
+	w.Write(buf[:n])
+}
+
+func (w binaryWriter) bytes(data []byte) {
+	w.uvarint(uint64(len(data)))
+	w.Write(data)
+}
+
+func (w binaryWriter) typeInfo(name string, tp reflect.Type, isRelation bool, raw bool) {
+	w.bytes([]byte(name))
+	if isRelation {
+		w.WriteByte(1)
+	} else {
+		w.WriteByte(0)
+	}
+	w.uvarint(uint64(tp.Size()))
+	if raw {
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], layoutFingerprint(tp))
+		w.Write(buf[:])
+		w.WriteByte(encodingRaw)
+	} else {
+		w.Write(make([]byte, 8))
+		w.WriteByte(encodingJSON)
+	}
+}
+
+// binaryReader reads primitives of the binary format.
+// After the first error, all reads return zero values, and the error is kept in err.
+type binaryReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *binaryReader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("unexpected end of binary data at byte %d", r.pos)
+	}
+}
+
+// next returns the next n bytes, or nil on error.
+func (r *binaryReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data)-r.pos {
+		r.fail()
+		return nil
+	}
+	buf := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return buf
+}
+
+func (r *binaryReader) byte() byte {
+	buf := r.next(1)
+	if buf == nil {
+		return 0
+	}
+	return buf[0]
+}
+
+func (r *binaryReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.pos += n
+	return value
+}
+
+// count reads a number of elements, each taking at least minSize bytes.
+// Fails if there are not enough bytes left, to avoid huge allocations for corrupt data.
+func (r *binaryReader) count(minSize int) int {
+	value := r.uvarint()
+	if r.err != nil {
+		return 0
+	}
+	if value > uint64(len(r.data)-r.pos)/uint64(minSize) {
+		r.fail()
+		return 0
+	}
+	return int(value)
+}
+
+func (r *binaryReader) bytes() []byte {
+	return r.next(r.count(1))
+}
+
+func (r *binaryReader) typeInfo() binaryType {
+	info := binaryType{}
+	info.name = string(r.bytes())
+	info.isRelation = r.byte() != 0
+	info.size = r.uvarint()
+	if buf := r.next(8); buf != nil {
+		info.fingerprint = binary.LittleEndian.Uint64(buf)
+	}
+	info.encoding = r.byte()
+	return info
+}
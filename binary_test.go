@@ -0,0 +1,255 @@
+package arkserde_test
+
+import (
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+type Name struct {
+	Name string
+}
+
+type Position3D struct {
+	X float64
+	Y float64
+	Z float64
+}
+
+func TestSerializeBinary(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Name](w).NewBatchFn(2, func(_ ecs.Entity, _ *Position, name *Name) { name.Name = "A" })
+	ecs.AddResource(w, &Velocity{X: 1000, Y: 1})
+	ecs.AddResource(w, &Name{Name: "World"})
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	data2, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+	assert.Equal(t, data, data2)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+
+	assertEqualWorlds(t, w, w2)
+	assert.Equal(t, Velocity{X: 1000, Y: 1}, *ecs.GetResource[Velocity](w2))
+	assert.Equal(t, Name{Name: "World"}, *ecs.GetResource[Name](w2))
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	assert.Less(t, len(data), len(jsonData))
+}
+
+func TestSerializeBinaryGZip(t *testing.T) {
+	w := createArchetypesWorld()
+
+	data, err := arkserde.SerializeBinary(w, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+}
+
+func TestSerializeBinarySkip(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Name](w).NewBatchFn(2, func(_ ecs.Entity, _ *Position, name *Name) { name.Name = "A" })
+	ecs.AddResource(w, &Velocity{X: 1000, Y: 1})
+	ecs.AddResource(w, &Name{Name: "World"})
+
+	data, err := arkserde.SerializeBinary(w,
+		arkserde.Opts.SkipComponents(ecs.C[Velocity]()),
+		arkserde.Opts.SkipResources(ecs.C[Name]()),
+	)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, countAll(w), countAll(w2))
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+	assert.Equal(t, countEntities[Position](w), countEntities[Position](w2))
+	assert.Equal(t, Name{}, *ecs.GetResource[Name](w2))
+
+	data, err = arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2,
+		arkserde.Opts.SkipComponents(ecs.C[Name]()),
+		arkserde.Opts.SkipAllResources(),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, countAll(w), countAll(w2))
+	assert.Equal(t, 0, countEntities[Name](w2))
+	assert.Equal(t, countEntities[Velocity](w), countEntities[Velocity](w2))
+	assert.Equal(t, Velocity{}, *ecs.GetResource[Velocity](w2))
+
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2, arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countAll(w2))
+	assert.Equal(t, Velocity{X: 1000, Y: 1}, *ecs.GetResource[Velocity](w2))
+
+	data, err = arkserde.SerializeBinary(w, arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countAll(w2))
+	assert.Equal(t, Name{Name: "World"}, *ecs.GetResource[Name](w2))
+}
+
+func TestDeserializeBinaryMigrate(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.AddResource(w, &Name{Name: "World"})
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2,
+		arkserde.Opts.SchemaVersion(1),
+		arkserde.Opts.Migrate(ecs.C[Name](), 0, func(jsonData []byte) ([]byte, error) {
+			return []byte(`{"Name":"X"}`), nil
+		}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, Name{Name: "X"}, *ecs.GetResource[Name](w2))
+
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2,
+		arkserde.Opts.SchemaVersion(1),
+		arkserde.Opts.Migrate(ecs.C[Position](), 0, func(jsonData []byte) ([]byte, error) {
+			return jsonData, nil
+		}),
+	)
+	assert.EqualError(t, err, "Types: arkserde_test.Position: can't migrate type, as it is stored as raw memory")
+}
+
+func TestDeserializeBinaryErrors(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Name](w).NewBatchFn(2, func(_ ecs.Entity, _ *Position, name *Name) { name.Name = "A" })
+	ecs.AddResource(w, &Velocity{X: 1000, Y: 1})
+	ecs.AddResource(w, &Name{Name: "World"})
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary([]byte("{}"), w2)
+	assert.EqualError(t, err, "data is not in the binary format")
+
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data[:len(data)/2], w2)
+	assert.ErrorContains(t, err, "unexpected end of binary data")
+	assert.Equal(t, 0, countAll(w2))
+
+	// Entities are not added if resources fail to load.
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data[:len(data)-2], w2)
+	assert.ErrorContains(t, err, "Resources: unexpected end of binary data")
+	assert.Equal(t, 0, countAll(w2))
+	assert.Equal(t, Velocity{}, *ecs.GetResource[Velocity](w2))
+
+	w2 = ecs.NewWorld(1024)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.EqualError(t, err, "Types: arkserde_test.Position: component type is not registered")
+
+	// Velocity is stored with a different layout.
+	w2 = createWorld(false)
+	_ = ecs.ComponentID[Position3D](w2)
+	_ = ecs.ComponentID[Name](w2)
+	err = arkserde.DeserializeBinary(data, w2, arkserde.Opts.Alias("arkserde_test.Velocity", ecs.C[Position3D]()))
+	assert.EqualError(t, err, "Types: arkserde_test.Velocity: memory layout does not match the serialized data")
+	assert.Equal(t, 0, countAll(w2))
+
+	// Name is stored as JSON.
+	w2 = createWorld(true)
+	_ = ecs.ComponentID[Position3D](w2)
+	err = arkserde.DeserializeBinary(data, w2, arkserde.Opts.Alias("arkserde_test.Name", ecs.C[Position3D]()))
+	assert.EqualError(t, err, "Types: arkserde_test.Name: type was stored as JSON, but is plain data")
+
+	w2 = newWorldLike(w)
+	w2.NewEntity()
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.EqualError(t, err, "World: world must not contain any alive or dead entities")
+	assert.Equal(t, 1, countAll(w2))
+}
+
+func benchmarkSerializeBinary(n int, b *testing.B) {
+	w := ecs.NewWorld(1024)
+
+	mapper := ecs.NewMap2[Position, Velocity](w)
+	mapper.NewBatchFn(n, nil)
+
+	for b.Loop() {
+		_, err := arkserde.SerializeBinary(w)
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+func BenchmarkSerializeBinary_100(b *testing.B) {
+	benchmarkSerializeBinary(100, b)
+}
+
+func BenchmarkSerializeBinary_1000(b *testing.B) {
+	benchmarkSerializeBinary(1000, b)
+}
+
+func BenchmarkSerializeBinary_10000(b *testing.B) {
+	benchmarkSerializeBinary(10000, b)
+}
+
+func BenchmarkSerializeBinary_100000(b *testing.B) {
+	benchmarkSerializeBinary(100000, b)
+}
+
+func benchmarkDeserializeBinary(n int, b *testing.B) {
+	w := ecs.NewWorld(1024)
+
+	mapper := ecs.NewMap2[Position, Velocity](w)
+	mapper.NewBatchFn(n, nil)
+
+	data, err := arkserde.SerializeBinary(w)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	_ = ecs.ComponentID[Velocity](w2)
+
+	for b.Loop() {
+		err = arkserde.DeserializeBinary(data, w2)
+		if err != nil {
+			panic(err.Error())
+		}
+		b.StopTimer()
+		w2.Reset()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkDeserializeBinary_100(b *testing.B) {
+	benchmarkDeserializeBinary(100, b)
+}
+
+func BenchmarkDeserializeBinary_1000(b *testing.B) {
+	benchmarkDeserializeBinary(1000, b)
+}
+
+func BenchmarkDeserializeBinary_10000(b *testing.B) {
+	benchmarkDeserializeBinary(10000, b)
+}
+
+func BenchmarkDeserializeBinary_100000(b *testing.B) {
+	benchmarkDeserializeBinary(100000, b)
+}
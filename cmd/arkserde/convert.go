@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+
+	"github.com/goccy/go-json"
+	arkserde "github.com/mlange-42/ark-serde"
+)
+
+func runPretty(args []string, e *env) error {
+	flags := newFlagSet("pretty", "[file]", e)
+	output := flags.String("o", "", "output file (default standard output)")
+	indent := flags.String("indent", "  ", "indentation string")
+	compact := flags.Bool("compact", false, "remove all insignificant whitespace instead")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := inputPath(flags)
+	if err != nil {
+		return err
+	}
+
+	data, _, err := readData(path, e)
+	if err != nil {
+		return err
+	}
+
+	buffer := bytes.Buffer{}
+	if *compact {
+		err = json.Compact(&buffer, data)
+	} else {
+		err = json.Indent(&buffer, data, "", *indent)
+	}
+	if err != nil {
+		return err
+	}
+	buffer.WriteByte('\n')
+	return writeFile(*output, buffer.Bytes(), e)
+}
+
+func runCompress(args []string, e *env) error {
+	flags := newFlagSet("compress", "[file]", e)
+	output := flags.String("o", "", "output file (default standard output)")
+	level := flags.Int("level", arkserde.DefaultCompression, "gzip compression level, from -2 to 9")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := inputPath(flags)
+	if err != nil {
+		return err
+	}
+
+	data, err := readFile(path, e)
+	if err != nil {
+		return err
+	}
+	if isGZip(data) {
+		return fmt.Errorf("input is already compressed")
+	}
+
+	buffer := bytes.Buffer{}
+	writer, err := gzip.NewWriterLevel(&buffer, *level)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return writeFile(*output, buffer.Bytes(), e)
+}
+
+func runDecompress(args []string, e *env) error {
+	flags := newFlagSet("decompress", "[file]", e)
+	output := flags.String("o", "", "output file (default standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := inputPath(flags)
+	if err != nil {
+		return err
+	}
+
+	data, compressed, err := readData(path, e)
+	if err != nil {
+		return err
+	}
+	if !compressed {
+		return fmt.Errorf("input is not compressed")
+	}
+	return writeFile(*output, data, e)
+}
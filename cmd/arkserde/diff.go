@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-json"
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// save holds the resources of a serialized world, which are compared in addition to the entities.
+type save struct {
+	Resources map[string]json.RawMessage
+}
+
+func runDiff(args []string, e *env) error {
+	flags := newFlagSet("diff", "<file1> <file2>", e)
+	values := flags.Bool("values", false, "print the values of changed components and resources")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return fmt.Errorf("expected two input files, got %d", flags.NArg())
+	}
+
+	saves := [2]*save{}
+	entities := [2]map[ecs.Entity]map[string][]byte{}
+	for i := range saves {
+		data, _, err := readData(flags.Arg(i), e)
+		if err != nil {
+			return err
+		}
+		saves[i] = &save{}
+		if err := json.Unmarshal(data, saves[i]); err != nil {
+			return fmt.Errorf("%s: %w", flags.Arg(i), err)
+		}
+		if entities[i], err = arkserde.InspectEntities(data); err != nil {
+			return fmt.Errorf("%s: %w", flags.Arg(i), err)
+		}
+	}
+
+	out := bytes.Buffer{}
+	for _, entity := range sortedEntities(entities[0], entities[1]) {
+		comps1, ok1 := entities[0][entity]
+		comps2, ok2 := entities[1][entity]
+		switch {
+		case !ok2:
+			fmt.Fprintf(&out, "- entity %v\n", entity)
+		case !ok1:
+			fmt.Fprintf(&out, "+ entity %v\n", entity)
+		default:
+			lines := diffValues(comps1, comps2, "    ", *values)
+			if len(lines) > 0 {
+				fmt.Fprintf(&out, "~ entity %v\n", entity)
+				out.WriteString(lines)
+			}
+		}
+	}
+
+	resources := [2]map[string][]byte{}
+	for i := range saves {
+		resources[i] = map[string][]byte{}
+		for name, value := range saves[i].Resources {
+			var err error
+			if resources[i][name], err = compact(value); err != nil {
+				return fmt.Errorf("%s: resource %s: %w", flags.Arg(i), name, err)
+			}
+		}
+	}
+	out.WriteString(diffValues(resources[0], resources[1], "resource ", *values))
+
+	if out.Len() == 0 {
+		return nil
+	}
+	fmt.Fprintf(e.stdout, "--- %s\n+++ %s\n", flags.Arg(0), flags.Arg(1))
+	if _, err := e.stdout.Write(out.Bytes()); err != nil {
+		return err
+	}
+	return errFound
+}
+
+// diffValues returns one line per added, removed or changed value.
+func diffValues(values1, values2 map[string][]byte, prefix string, withValues bool) string {
+	names := []string{}
+	for name := range values1 {
+		names = append(names, name)
+	}
+	for name := range values2 {
+		if _, ok := values1[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	b := strings.Builder{}
+	for _, name := range names {
+		v1, ok1 := values1[name]
+		v2, ok2 := values2[name]
+		switch {
+		case !ok2:
+			fmt.Fprintf(&b, "%s- %s\n", prefix, name)
+		case !ok1:
+			fmt.Fprintf(&b, "%s+ %s\n", prefix, name)
+		case !bytes.Equal(v1, v2):
+			if withValues {
+				fmt.Fprintf(&b, "%s~ %s: %s -> %s\n", prefix, name, v1, v2)
+			} else {
+				fmt.Fprintf(&b, "%s~ %s\n", prefix, name)
+			}
+		}
+	}
+	return b.String()
+}
+
+// sortedEntities returns the entities of both maps, sorted by ID and generation.
+func sortedEntities(entities1, entities2 map[ecs.Entity]map[string][]byte) []ecs.Entity {
+	result := make([]ecs.Entity, 0, len(entities1))
+	for entity := range entities1 {
+		result = append(result, entity)
+	}
+	for entity := range entities2 {
+		if _, ok := entities1[entity]; !ok {
+			result = append(result, entity)
+		}
+	}
+	slices.SortFunc(result, func(a, b ecs.Entity) int {
+		return cmp.Or(cmp.Compare(a.ID(), b.ID()), cmp.Compare(a.Gen(), b.Gen()))
+	})
+	return result
+}
+
+// compact removes insignificant whitespace from JSON, so that values can be compared.
+func compact(value []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if err := json.Compact(&buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/goccy/go-json"
+	arkserde "github.com/mlange-42/ark-serde"
+)
+
+func runInspect(args []string, e *env) error {
+	flags := newFlagSet("inspect", "[file]", e)
+	asJSON := flags.Bool("json", false, "print the statistics as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := inputPath(flags)
+	if err != nil {
+		return err
+	}
+
+	data, err := readFile(path, e)
+	if err != nil {
+		return err
+	}
+	info, err := arkserde.Inspect(data)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		jsonData, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(e.stdout, "%s\n", jsonData)
+		return err
+	}
+
+	layout := "default"
+	if info.Columnar {
+		layout = "columnar"
+	}
+
+	w := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Format version:\t%d\n", info.FormatVersion)
+	fmt.Fprintf(w, "Schema version:\t%d\n", info.SchemaVersion)
+	fmt.Fprintf(w, "Compressed:\t%t\n", info.Compressed)
+	fmt.Fprintf(w, "Layout:\t%s\n", layout)
+	fmt.Fprintf(w, "Entities:\t%d (%d alive, %d dead)\n", info.Entities, info.Alive, info.Dead)
+
+	fmt.Fprintf(w, "\nTypes (%d):\t\n", len(info.Types))
+	for _, name := range info.Types {
+		fmt.Fprintf(w, "  %s\t%d\n", name, info.TypeCounts[name])
+	}
+	fmt.Fprintf(w, "\nResources (%d):\t\n", len(info.Resources))
+	for _, name := range info.Resources {
+		fmt.Fprintf(w, "  %s\t\n", name)
+	}
+	fmt.Fprintf(w, "\nSections:\t\n")
+	for _, section := range info.Sections {
+		fmt.Fprintf(w, "  %s\t%d bytes\n", section.Name, section.Size)
+	}
+	return w.Flush()
+}
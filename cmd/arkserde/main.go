@@ -0,0 +1,171 @@
+// Command arkserde inspects and converts files written by ark-serde, without Go type information.
+//
+// Usage:
+//
+//	arkserde <command> [flags] [file...]
+//
+// Commands:
+//
+//	inspect     Print entity, type, resource and section statistics
+//	pretty      Reformat JSON, indented or compact
+//	compress    Compress a file with gzip
+//	decompress  Decompress a gzip-compressed file
+//	diff        Compare two files by entity and component
+//	validate    Check the structure of a file
+//
+// Files can be compressed or not; compression is detected automatically.
+// Use "-" or omit the file to read from standard input.
+// Run "arkserde <command> -h" for the flags of a command.
+//
+// The exit code is 0 on success, 1 if diff found differences or validate found problems,
+// and 2 on errors.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errFound signals that differences or problems were found, and already reported.
+var errFound = errors.New("found differences or problems")
+
+// command is a subcommand of the tool.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, env *env) error
+}
+
+var commands = []command{
+	{"inspect", "Print entity, type, resource and section statistics", runInspect},
+	{"pretty", "Reformat JSON, indented or compact", runPretty},
+	{"compress", "Compress a file with gzip", runCompress},
+	{"decompress", "Decompress a gzip-compressed file", runDecompress},
+	{"diff", "Compare two files by entity and component", runDiff},
+	{"validate", "Check the structure of a file", runValidate},
+}
+
+// env holds the standard streams of the tool.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}))
+}
+
+// run runs the tool with the given arguments, and returns the exit code.
+func run(args []string, e *env) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		printUsage(e.stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:], e)
+		if err == nil {
+			return 0
+		}
+		if errors.Is(err, errFound) {
+			return 1
+		}
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(e.stderr, "arkserde %s: %s\n", cmd.name, err)
+		}
+		return 2
+	}
+
+	fmt.Fprintf(e.stderr, "arkserde: unknown command %q\n", args[0])
+	printUsage(e.stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: arkserde <command> [flags] [file...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-11s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'arkserde <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates the flag set of a command.
+func newFlagSet(name, args string, e *env) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: arkserde %s [flags] %s\n", name, args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// inputPath returns the single input file of a command, or "-" for standard input.
+func inputPath(flags *flag.FlagSet) (string, error) {
+	switch flags.NArg() {
+	case 0:
+		return "-", nil
+	case 1:
+		return flags.Arg(0), nil
+	default:
+		return "", fmt.Errorf("expected one input file, got %d", flags.NArg())
+	}
+}
+
+// readFile reads a file, or standard input for "-".
+func readFile(path string, e *env) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readData reads a file, and decompresses it if it is gzip-compressed.
+// Returns whether the file was compressed.
+func readData(path string, e *env) ([]byte, bool, error) {
+	data, err := readFile(path, e)
+	if err != nil {
+		return nil, false, err
+	}
+	if !isGZip(data) {
+		return data, false, nil
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	data, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, reader.Close()
+}
+
+// writeFile writes to a file, or to standard output for "" or "-".
+func writeFile(path string, data []byte, e *env) error {
+	if path == "" || path == "-" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// isGZip checks whether data starts with the gzip magic number.
+func isGZip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+type Position struct {
+	X, Y float64
+}
+
+type Velocity struct {
+	X, Y float64
+}
+
+type ChildOf struct {
+	ecs.RelationMarker
+}
+
+func createWorld() *ecs.World {
+	w := ecs.NewWorld(1024)
+	posMap := ecs.NewMap1[Position](w)
+	posVelMap := ecs.NewMap2[Position, Velocity](w)
+	childMap := ecs.NewMap2[Position, ChildOf](w)
+
+	parent := posMap.NewEntity(&Position{X: 1})
+	posVelMap.NewEntity(&Position{X: 2}, &Velocity{X: 3})
+	childMap.NewEntity(&Position{X: 4}, &ChildOf{}, ecs.RelIdx(1, parent))
+	ecs.AddResource(w, &Velocity{X: 10})
+	return w
+}
+
+// writeSave serializes the world to a file in a temporary directory.
+func writeSave(t *testing.T, w *ecs.World, name string, options ...arkserde.Option) string {
+	t.Helper()
+	data, err := arkserde.Serialize(w, options...)
+	assert.Nil(t, err)
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+// runTool runs the tool, and returns the exit code and the standard output and error.
+func runTool(stdin []byte, args ...string) (int, string, string) {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	code := run(args, &env{stdin: bytes.NewReader(stdin), stdout: &stdout, stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestUsage(t *testing.T) {
+	code, _, stderr := runTool(nil)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: arkserde <command>")
+
+	code, _, _ = runTool(nil, "help")
+	assert.Equal(t, 0, code)
+
+	code, _, stderr = runTool(nil, "foo")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown command \"foo\"")
+
+	code, _, stderr = runTool(nil, "inspect", "-h")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: arkserde inspect [flags] [file]")
+
+	code, _, stderr = runTool(nil, "inspect", "a", "b")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "expected one input file, got 2")
+}
+
+func TestInspect(t *testing.T) {
+	w := createWorld()
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+		{arkserde.Opts.Compress()},
+	} {
+		path := writeSave(t, w, "save.json", opts...)
+
+		code, stdout, stderr := runTool(nil, "inspect", path)
+		assert.Equal(t, 0, code, stderr)
+		assert.Contains(t, stdout, "Entities:        3 (3 alive, 0 dead)")
+		assert.Contains(t, stdout, "  main.Position  3\n")
+		assert.Contains(t, stdout, "  main.Velocity  1\n")
+		assert.Contains(t, stdout, "Resources (1):")
+
+		code, stdout, _ = runTool(nil, "inspect", "-json", path)
+		assert.Equal(t, 0, code)
+		assert.Contains(t, stdout, "\"Alive\": 3")
+	}
+
+	code, _, stderr := runTool([]byte("{"), "inspect")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "arkserde inspect: ")
+}
+
+func TestPretty(t *testing.T) {
+	path := writeSave(t, createWorld(), "save.json.gz", arkserde.Opts.Compress())
+
+	code, stdout, _ := runTool(nil, "pretty", path)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "{\n  \"Version\": {\n    \"Format\": 1,")
+
+	code, compact, _ := runTool([]byte(stdout), "pretty", "-compact")
+	assert.Equal(t, 0, code)
+	assert.True(t, strings.HasPrefix(compact, "{\"Version\":{\"Format\":1,"))
+	assert.Equal(t, 1, strings.Count(compact, "\n"))
+
+	out := filepath.Join(t.TempDir(), "pretty.json")
+	code, _, _ = runTool([]byte(stdout), "pretty", "-indent", "\t", "-o", out)
+	assert.Equal(t, 0, code)
+	data, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "\n\t\"World\": {")
+
+	code, _, _ = runTool([]byte("{"), "pretty")
+	assert.Equal(t, 2, code)
+}
+
+func TestCompress(t *testing.T) {
+	w := createWorld()
+	path := writeSave(t, w, "save.json")
+	original, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	out := filepath.Join(t.TempDir(), "save.json.gz")
+	code, _, stderr := runTool(nil, "compress", "-level", "9", "-o", out, path)
+	assert.Equal(t, 0, code, stderr)
+
+	// Compressed files can be read with the compression option.
+	compressed, err := os.ReadFile(out)
+	assert.Nil(t, err)
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	_ = ecs.ComponentID[Velocity](w2)
+	_ = ecs.ComponentID[ChildOf](w2)
+	ecs.AddResource(w2, &Velocity{})
+	assert.Nil(t, arkserde.Deserialize(compressed, w2, arkserde.Opts.Compress()))
+	assert.Equal(t, Velocity{X: 10}, *ecs.GetResource[Velocity](w2))
+
+	code, stdout, _ := runTool(compressed, "decompress")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, string(original), stdout)
+
+	code, _, stderr = runTool(compressed, "compress")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "input is already compressed")
+
+	code, _, stderr = runTool(original, "decompress")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "input is not compressed")
+
+	code, _, stderr = runTool(original, "compress", "-level", "20")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "invalid compression level")
+}
+
+func TestDiff(t *testing.T) {
+	w := createWorld()
+	path1 := writeSave(t, w, "save1.json")
+
+	code, stdout, _ := runTool(nil, "diff", path1, writeSave(t, w, "same.json", arkserde.Opts.Columnar(), arkserde.Opts.Compress()))
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout)
+
+	query := ecs.NewFilter2[Position, Velocity](w).Query()
+	query.Next()
+	entity := query.Entity()
+	query.Close()
+	ecs.NewMap[Position](w).Get(entity).X = 100
+	ecs.NewMap[Velocity](w).Remove(entity)
+	childQuery := ecs.NewFilter1[ChildOf](w).Query()
+	childQuery.Next()
+	child := childQuery.Entity()
+	childQuery.Close()
+	w.RemoveEntity(child)
+	ecs.NewMap1[Velocity](w).NewEntity(&Velocity{})
+	ecs.GetResource[Velocity](w).Y = 1
+
+	path2 := writeSave(t, w, "save2.json", arkserde.Opts.Columnar())
+	code, stdout, _ = runTool(nil, "diff", path1, path2)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "--- "+path1+"\n+++ "+path2+"\n"+
+		"~ entity {3 0}\n"+
+		"    ~ main.Position\n"+
+		"    - main.Velocity\n"+
+		"- entity {4 0}\n"+
+		"+ entity {4 1}\n"+
+		"resource ~ main.Velocity\n", stdout)
+
+	code, stdout, _ = runTool(nil, "diff", "-values", path1, path2)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "    ~ main.Position: {\"X\":2,\"Y\":0} -> {\"X\":100,\"Y\":0}\n")
+
+	code, _, stderr := runTool(nil, "diff", path1)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "expected two input files, got 1")
+}
+
+func TestValidate(t *testing.T) {
+	path := writeSave(t, createWorld(), "save.json", arkserde.Opts.SchemaVersion(3))
+
+	code, stdout, _ := runTool(nil, "validate", path)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ok\n", stdout)
+
+	code, stdout, _ = runTool(nil, "validate", "-schema", "2", path)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "Version: data has schema version 3, but the current schema version is 2\nfound 1 problem(s)\n", stdout)
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	data = bytes.Replace(data, []byte("\"Alive\":[2,3,4]"), []byte("\"Alive\":[2,3]"), 1)
+	code, stdout, _ = runTool(data, "validate")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout, "Components: found components for 3 entities, but world has 2 alive entities")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	arkserde "github.com/mlange-42/ark-serde"
+)
+
+func runValidate(args []string, e *env) error {
+	flags := newFlagSet("validate", "[file]", e)
+	schema := flags.Int("schema", -1, "current schema version; newer data is reported (default no check)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	path, err := inputPath(flags)
+	if err != nil {
+		return err
+	}
+
+	data, _, err := readData(path, e)
+	if err != nil {
+		return err
+	}
+
+	version := *schema
+	if version < 0 {
+		version = math.MaxInt32
+	}
+	problems := arkserde.Validate(data, nil, arkserde.Opts.SchemaVersion(version))
+	for _, p := range problems {
+		fmt.Fprintln(e.stdout, p.Error())
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(e.stdout, "found %d problem(s)\n", len(problems))
+		return errFound
+	}
+	_, err = fmt.Fprintln(e.stdout, "ok")
+	return err
+}
@@ -0,0 +1,54 @@
+package arkserde
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/goccy/go-json"
+)
+
+// Encoder encodes a component or resource to JSON, for use with [Options.Codec].
+// The argument is a pointer to the value.
+// The result must be valid JSON.
+type Encoder func(value interface{}) ([]byte, error)
+
+// Decoder decodes a component or resource from JSON, for use with [Options.Codec].
+// The argument is a pointer to the value to decode into.
+type Decoder func(jsonData []byte, value interface{}) error
+
+type codec struct {
+	encode Encoder
+	decode Decoder
+}
+
+// encodeValue encodes the value of the given type at ptr.
+// Calls the [BeforeSerializer] hook of the value first, if it has one.
+// Uses the codec for the type, if there is one.
+func encodeValue(tp reflect.Type, ptr unsafe.Pointer, opts *serdeOptions) ([]byte, error) {
+	value := reflect.NewAt(tp, ptr).Interface()
+	if hook, ok := value.(BeforeSerializer); ok {
+		hook.BeforeSerialize()
+	}
+	c, ok := opts.codecs[tp]
+	if !ok {
+		return json.Marshal(value)
+	}
+	jsonData, err := c.encode(value)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(jsonData) {
+		return nil, fmt.Errorf("codec for %s produced invalid JSON", tp)
+	}
+	return jsonData, nil
+}
+
+// decodeValue decodes JSON into the value of the given type pointed to by value.
+// Uses the codec for the type, if there is one.
+func decodeValue(tp reflect.Type, jsonData []byte, value interface{}, opts *serdeOptions) error {
+	if c, ok := opts.codecs[tp]; ok {
+		return c.decode(jsonData, value)
+	}
+	return json.Unmarshal(jsonData, value)
+}
@@ -0,0 +1,179 @@
+package arkserde_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/goccy/go-json"
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+type Shape interface {
+	Area() float64
+}
+
+type Circle struct {
+	Radius float64
+}
+
+func (c Circle) Area() float64 { return 3 * c.Radius * c.Radius }
+
+type Square struct {
+	Side float64
+}
+
+func (s Square) Area() float64 { return s.Side * s.Side }
+
+// Geometry can't be decoded with the default encoding, due to the interface field.
+type Geometry struct {
+	Shape Shape
+}
+
+type geometryJSON struct {
+	Kind string
+	Size float64
+}
+
+func encodeGeometry(value interface{}) ([]byte, error) {
+	g := value.(*Geometry)
+	switch s := g.Shape.(type) {
+	case Circle:
+		return json.Marshal(geometryJSON{Kind: "circle", Size: s.Radius})
+	case Square:
+		return json.Marshal(geometryJSON{Kind: "square", Size: s.Side})
+	case nil:
+		return []byte("null"), nil
+	}
+	return nil, fmt.Errorf("unknown shape %T", g.Shape)
+}
+
+func decodeGeometry(jsonData []byte, value interface{}) error {
+	g := value.(*Geometry)
+	var helper *geometryJSON
+	if err := json.Unmarshal(jsonData, &helper); err != nil {
+		return err
+	}
+	if helper == nil {
+		g.Shape = nil
+		return nil
+	}
+	switch helper.Kind {
+	case "circle":
+		g.Shape = Circle{Radius: helper.Size}
+	case "square":
+		g.Shape = Square{Side: helper.Size}
+	default:
+		return fmt.Errorf("unknown shape %s", helper.Kind)
+	}
+	return nil
+}
+
+var geometryCodec = arkserde.Opts.Codec(ecs.C[Geometry](), encodeGeometry, decodeGeometry)
+
+func TestCodec(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{geometryCodec},
+		{geometryCodec, arkserde.Opts.Columnar()},
+	} {
+		w := createArchetypesWorld()
+		geoMap := ecs.NewMap2[Position, Geometry](w)
+		geoMap.NewEntity(&Position{X: 1}, &Geometry{Shape: Circle{Radius: 2}})
+		geoMap.NewEntity(&Position{X: 2}, &Geometry{Shape: Square{Side: 3}})
+		geoMap.NewEntity(&Position{X: 3}, &Geometry{})
+		ecs.AddResource(w, &Geometry{Shape: Square{Side: 10}})
+
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+		assert.Contains(t, string(jsonData), `{"Kind":"circle","Size":2}`)
+
+		w2 := newWorldLike(w)
+		err = arkserde.Deserialize(jsonData, w2, opts...)
+		assert.Nil(t, err)
+
+		assertEqualWorlds(t, w, w2)
+		assert.Equal(t, Geometry{Shape: Square{Side: 10}}, *ecs.GetResource[Geometry](w2))
+	}
+}
+
+func TestCodecBinary(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Geometry](w).NewEntity(&Position{X: 1}, &Geometry{Shape: Circle{Radius: 2}})
+	ecs.AddResource(w, &Geometry{Shape: Square{Side: 10}})
+
+	// Position has a codec, so it is stored as JSON instead of raw memory.
+	posCodec := arkserde.Opts.Codec(ecs.C[Position](),
+		func(value interface{}) ([]byte, error) {
+			p := value.(*Position)
+			return json.Marshal([]float64{p.X, p.Y})
+		},
+		func(jsonData []byte, value interface{}) error {
+			xy := []float64{}
+			if err := json.Unmarshal(jsonData, &xy); err != nil {
+				return err
+			}
+			*value.(*Position) = Position{X: xy[0], Y: xy[1]}
+			return nil
+		},
+	)
+
+	data, err := arkserde.SerializeBinary(w, geometryCodec, posCodec)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2, geometryCodec, posCodec)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+	assert.Equal(t, Geometry{Shape: Square{Side: 10}}, *ecs.GetResource[Geometry](w2))
+
+	w2 = newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2, geometryCodec)
+	assert.EqualError(t, err, "Types: arkserde_test.Position: type was stored as JSON, but is plain data")
+}
+
+func TestCodecPrefab(t *testing.T) {
+	w := createWorld(false)
+	geoMap := ecs.NewMap2[Position, Geometry](w)
+	geoMap.NewEntity(&Position{X: 1}, &Geometry{Shape: Circle{Radius: 2}})
+	geoMap.NewEntity(&Position{X: 2}, &Geometry{Shape: Square{Side: 3}})
+	geoMap.NewEntity(&Position{X: 3}, &Geometry{})
+
+	roots := []ecs.Entity{}
+	query := ecs.NewFilter1[Geometry](w).Query()
+	for query.Next() {
+		roots = append(roots, query.Entity())
+	}
+
+	jsonData, err := arkserde.SerializeEntities(w, roots, geometryCodec)
+	assert.Nil(t, err)
+
+	entities, err := arkserde.Instantiate(jsonData, w, geometryCodec)
+	assert.Nil(t, err)
+	assert.Len(t, entities, 3)
+	assert.Equal(t, Geometry{Shape: Circle{Radius: 2}}, *ecs.NewMap[Geometry](w).Get(entities[0]))
+}
+
+func TestCodecErrors(t *testing.T) {
+	w := createWorld(false)
+	ecs.NewMap2[Position, Geometry](w).NewEntity(&Position{X: 1}, &Geometry{Shape: Circle{Radius: 2}})
+
+	invalid := arkserde.Opts.Codec(ecs.C[Geometry](),
+		func(value interface{}) ([]byte, error) { return []byte("{"), nil },
+		decodeGeometry,
+	)
+	_, err := arkserde.Serialize(w, invalid)
+	assert.EqualError(t, err, "codec for arkserde_test.Geometry produced invalid JSON")
+
+	failing := arkserde.Opts.Codec(ecs.C[Geometry](),
+		encodeGeometry,
+		func(jsonData []byte, value interface{}) error { return fmt.Errorf("decoding failed") },
+	)
+	jsonData, err := arkserde.Serialize(w, geometryCodec)
+	assert.Nil(t, err)
+	err = arkserde.Deserialize(jsonData, newWorldLike(w), failing)
+	assert.ErrorContains(t, err, "decoding failed")
+
+	_, err = arkserde.SerializeBinary(w, invalid)
+	assert.EqualError(t, err, "codec for arkserde_test.Geometry produced invalid JSON")
+}
@@ -0,0 +1,484 @@
+package arkserde
+
+import (
+	"bufio"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// archetype is the serialized form of a group of entities
+// with the same components and relation targets,
+// used by the columnar layout (see [Options.Columnar]).
+type archetype struct {
+	Entities []int                 // Indices of the entities in the list of alive entities.
+	Targets  map[string]ecs.Entity // Relation targets, by relation component name.
+	Columns  map[string]entry      // Component columns, by component name.
+}
+
+// archetypeGroup is a group of consecutive entities with the same components and relation targets.
+type archetypeGroup struct {
+	ids      []ecs.ID     // Component IDs, without skipped components.
+	targets  []ecs.Entity // Relation targets, in the order of relation components in ids.
+	entities []ecs.Entity // Entities of the group.
+	indices  []int        // Indices of the entities in the list of alive entities.
+	unknown  string       // Signature of preserved components of unknown types, see [unknownComponents.signature].
+}
+
+// matches checks whether the group has the given components and relation targets.
+func (g *archetypeGroup) matches(ids []ecs.ID, targets []ecs.Entity, unknown string) bool {
+	if len(g.entities) == 0 || len(ids) != len(g.ids) || len(targets) != len(g.targets) || unknown != g.unknown {
+		return false
+	}
+	for i, id := range ids {
+		if id != g.ids[i] {
+			return false
+		}
+	}
+	for i, target := range targets {
+		if target != g.targets[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// forEachArchetype groups consecutive entities in query order by their components and relation targets,
+// including preserved components of unknown types, and calls fn for each group.
+// The group is re-used between calls.
+func forEachArchetype(world *ecs.World, infos []ecs.CompInfo, opts *serdeOptions, selection *entitySelection, unknown *unknownComponents, fn func(group *archetypeGroup) error) error {
+	skipComponents := skipMask(world, opts)
+
+	group := archetypeGroup{}
+	flush := func() error {
+		if len(group.entities) == 0 {
+			return nil
+		}
+		if err := fn(&group); err != nil {
+			return err
+		}
+		group.entities = group.entities[:0]
+		group.indices = group.indices[:0]
+		return nil
+	}
+
+	query := ecs.NewUnsafeFilter(world).Query()
+	tempIDs := []ecs.ID{}
+	tempTargets := []ecs.Entity{}
+	index := 0
+	for query.Next() {
+		if !selection.contains(query.Entity()) {
+			continue
+		}
+		tempIDs = tempIDs[:0]
+		tempTargets = tempTargets[:0]
+		if !opts.skipAllComponents {
+			ids := query.IDs()
+			for i := range ids.Len() {
+				id := ids.Get(i)
+				if skipComponents.Get(id) {
+					continue
+				}
+				tempIDs = append(tempIDs, id)
+				if infos[id.Index()].IsRelation {
+					tempTargets = append(tempTargets, selection.target(query.GetRelation(id)))
+				}
+			}
+		}
+
+		signature, err := unknown.signature(query.Entity())
+		if err != nil {
+			query.Close()
+			return err
+		}
+
+		if !group.matches(tempIDs, tempTargets, signature) {
+			if err := flush(); err != nil {
+				query.Close()
+				return err
+			}
+			group.ids = append(group.ids[:0], tempIDs...)
+			group.targets = append(group.targets[:0], tempTargets...)
+			group.unknown = signature
+		}
+		group.entities = append(group.entities, query.Entity())
+		group.indices = append(group.indices, index)
+		index++
+	}
+	return flush()
+}
+
+// componentInfos returns the infos of all registered component types, indexed by component ID.
+func componentInfos(world *ecs.World) []ecs.CompInfo {
+	allComps := ecs.ComponentIDs(world)
+	infos := make([]ecs.CompInfo, len(allComps))
+	for _, id := range allComps {
+		infos[id.Index()], _ = ecs.ComponentInfo(world, id)
+	}
+	return infos
+}
+
+func serializeArchetypes(world *ecs.World, writer *bufio.Writer, opts *serdeOptions, selection *entitySelection, unknown *unknownComponents) error {
+	if opts.skipEntities {
+		writer.WriteString("\"Archetypes\" : []")
+		return nil
+	}
+
+	names := componentNames(world, opts)
+	infos := componentInfos(world)
+	u := world.Unsafe()
+
+	writer.WriteString("\"Archetypes\" : [\n")
+
+	count := 0
+	err := forEachArchetype(world, infos, opts, selection, unknown, func(group *archetypeGroup) error {
+		if count > 0 {
+			writer.WriteString(",\n")
+		}
+		count++
+
+		writer.WriteString("  {\n")
+
+		writer.WriteString("    \"Entities\" : [")
+		for i, idx := range group.indices {
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString(strconv.Itoa(idx))
+		}
+		writer.WriteString("],\n")
+
+		writer.WriteString("    \"Targets\" : {")
+		relIndex := 0
+		for _, id := range group.ids {
+			if !infos[id.Index()].IsRelation {
+				continue
+			}
+			eJSON, err := group.targets[relIndex].MarshalJSON()
+			if err != nil {
+				return err
+			}
+			if relIndex > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString("\n      \"")
+			writer.WriteString(names[id.Index()])
+			writer.WriteString("\" : ")
+			writer.Write(eJSON)
+			relIndex++
+		}
+
+		// Preserved components of unknown types are the same for all entities of the group.
+		unknownKeys := unknown.keys(group.entities[0])
+		for _, key := range unknownKeys {
+			name, ok := strings.CutSuffix(key, targetTag)
+			if !ok {
+				continue
+			}
+			eJSON, err := unknown.value(group.entities[0], key)
+			if err != nil {
+				return err
+			}
+			if relIndex > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString("\n      \"")
+			writer.WriteString(name)
+			writer.WriteString("\" : ")
+			writer.Write(eJSON)
+			relIndex++
+		}
+		if relIndex > 0 {
+			writer.WriteString("\n    ")
+		}
+		writer.WriteString("},\n")
+
+		writer.WriteString("    \"Columns\" : {")
+		for i, id := range group.ids {
+			info := infos[id.Index()]
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString("\n      \"")
+			writer.WriteString(names[id.Index()])
+			writer.WriteString("\" : [")
+			for j, entity := range group.entities {
+				jsonData, err := encodeValue(info.Type, u.Get(entity, id), opts)
+				if err != nil {
+					return err
+				}
+				if j > 0 {
+					writer.WriteByte(',')
+				}
+				writer.Write(jsonData)
+			}
+			writer.WriteString("]")
+		}
+		numColumns := len(group.ids)
+		for _, key := range unknownKeys {
+			if strings.HasSuffix(key, targetTag) {
+				continue
+			}
+			if numColumns > 0 {
+				writer.WriteByte(',')
+			}
+			writer.WriteString("\n      \"")
+			writer.WriteString(key)
+			writer.WriteString("\" : [")
+			for j, entity := range group.entities {
+				jsonData, err := unknown.value(entity, key)
+				if err != nil {
+					return err
+				}
+				if j > 0 {
+					writer.WriteByte(',')
+				}
+				writer.Write(jsonData)
+			}
+			writer.WriteString("]")
+			numColumns++
+		}
+		if numColumns > 0 {
+			writer.WriteString("\n    ")
+		}
+		writer.WriteString("}\n")
+
+		writer.WriteString("  }")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		writer.WriteString("\n")
+	}
+
+	writer.WriteString("]")
+	return nil
+}
+
+// decodedArchetype holds the decoded columns of an archetype, before they are added to the world.
+type decodedArchetype struct {
+	entities []int               // Indices in the list of alive entities.
+	ids      []ecs.ID            // Component IDs of the columns.
+	targets  []ecs.Entity        // Relation targets before remapping, by column.
+	columns  []reflect.Value     // Slices of decoded components.
+	unknown  []map[string][]byte // Preserved components of unknown types, by entity. Nil if there are none.
+	// Preserved relation targets of unknown types, by key with the target tag, before remapping.
+	unknownTargets map[string]ecs.Entity
+}
+
+// loadArchetype adds the components of an archetype to its entities.
+func (l *entityLoader) loadArchetype(index int, arch *archetype) error {
+	decoded, err := l.decodeArchetype(index, arch)
+	if err != nil {
+		return err
+	}
+	l.addArchetype(&decoded)
+	return nil
+}
+
+// decodeArchetype decodes the columns of the archetype at the given index.
+func (l *entityLoader) decodeArchetype(index int, arch *archetype) (decodedArchetype, error) {
+	if err := l.markLoaded(arch.Entities); err != nil {
+		return decodedArchetype{}, newDecodeError("Archetypes", index, "", err)
+	}
+
+	decoded := decodedArchetype{
+		entities: arch.Entities,
+		ids:      make([]ecs.ID, 0, len(arch.Columns)),
+		targets:  make([]ecs.Entity, 0, len(arch.Columns)),
+		columns:  make([]reflect.Value, 0, len(arch.Columns)),
+	}
+
+	// Relation targets, by the ID of their relation component.
+	// Targets and columns are processed in the order of their names, so that errors are reported deterministically.
+	targets := make(map[ecs.ID]ecs.Entity, len(arch.Targets))
+	for _, tpName := range sortedKeys(arch.Targets) {
+		target := arch.Targets[tpName]
+		if l.isUnknown(tpName) {
+			continue
+		}
+		id, err := l.targetID(tpName)
+		if err != nil {
+			return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, err)
+		}
+		targets[id] = target
+	}
+
+	found := bitMask{}
+	for _, tpName := range sortedKeys(arch.Columns) {
+		column := arch.Columns[tpName]
+		if l.isUnknown(tpName) {
+			if err := l.preserveColumn(&decoded, arch, tpName, column.Bytes); err != nil {
+				return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, err)
+			}
+			continue
+		}
+		id, ok, err := l.componentID(tpName, &found)
+		if err != nil {
+			return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, err)
+		}
+		if !ok {
+			continue
+		}
+		info := l.infos[id.Index()]
+
+		target := ecs.Entity{}
+		if info.IsRelation {
+			if target, ok = targets[id]; !ok {
+				return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, fmt.Errorf("missing relation target"))
+			}
+			if err := l.checkTarget(target); err != nil {
+				return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, err)
+			}
+		}
+
+		values, err := l.decodeColumn(info.Type, column.Bytes)
+		if err != nil {
+			return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, err)
+		}
+		if values.Len() != len(arch.Entities) {
+			return decodedArchetype{}, newDecodeError("Archetypes", index, tpName, fmt.Errorf("found %d values, but archetype has %d entities", values.Len(), len(arch.Entities)))
+		}
+
+		decoded.ids = append(decoded.ids, id)
+		decoded.targets = append(decoded.targets, target)
+		decoded.columns = append(decoded.columns, values)
+	}
+	return decoded, nil
+}
+
+// preserveColumn keeps the JSON of a column of an unknown type, and its relation target,
+// with policy [UnknownPreserve].
+func (l *entityLoader) preserveColumn(decoded *decodedArchetype, arch *archetype, tpName string, jsonData []byte) error {
+	if l.opts.componentPolicy(tpName) != UnknownPreserve {
+		return nil
+	}
+	elements := []entry{}
+	if err := json.Unmarshal(jsonData, &elements); err != nil {
+		return err
+	}
+	if len(elements) != len(arch.Entities) {
+		return fmt.Errorf("found %d values, but archetype has %d entities", len(elements), len(arch.Entities))
+	}
+	if target, ok := arch.Targets[tpName]; ok {
+		if decoded.unknownTargets == nil {
+			decoded.unknownTargets = map[string]ecs.Entity{}
+		}
+		decoded.unknownTargets[tpName+targetTag] = target
+	}
+
+	if decoded.unknown == nil {
+		decoded.unknown = make([]map[string][]byte, len(arch.Entities))
+	}
+	for i, elem := range elements {
+		value, err := compactJSON(elem.Bytes)
+		if err != nil {
+			return err
+		}
+		if decoded.unknown[i] == nil {
+			decoded.unknown[i] = map[string][]byte{}
+		}
+		decoded.unknown[i][tpName] = value
+	}
+	return nil
+}
+
+// addArchetype adds the decoded columns of an archetype to its entities.
+func (l *entityLoader) addArchetype(decoded *decodedArchetype) {
+	if decoded.unknown != nil {
+		data := preserveUnknown(l.world)
+		for i, idx := range decoded.entities {
+			data.addComponents(l.entity(idx), decoded.unknown[i], decoded.unknownTargets, l.remap)
+		}
+	}
+	if len(decoded.ids) == 0 {
+		return
+	}
+
+	relations := []ecs.Relation{}
+	for i, id := range decoded.ids {
+		if l.infos[id.Index()].IsRelation {
+			relations = append(relations, ecs.RelID(id, l.remap.entity(decoded.targets[i])))
+		}
+	}
+	for _, values := range decoded.columns {
+		l.remap.value(values)
+	}
+
+	u := l.world.Unsafe()
+	for i, idx := range decoded.entities {
+		entity := l.entity(idx)
+		u.AddRel(entity, decoded.ids, relations...)
+		for j, id := range decoded.ids {
+			dst := u.Get(entity, id)
+			value := decoded.columns[j].Index(i)
+			reflect.NewAt(value.Type(), dst).Elem().Set(value)
+		}
+	}
+}
+
+// markLoaded marks the entities with the given indices as loaded.
+// Returns an error if an index is out of range, or if an entity was already loaded.
+func (l *entityLoader) markLoaded(indices []int) error {
+	for _, idx := range indices {
+		if idx < 0 || idx >= len(l.entities.Alive) {
+			return fmt.Errorf("entity index %d out of range for %d alive entities", idx, len(l.entities.Alive))
+		}
+		if l.loaded[idx] {
+			return fmt.Errorf("entity index %d appears in more than one archetype", idx)
+		}
+		l.loaded[idx] = true
+	}
+	return nil
+}
+
+// decodeColumn decodes a column of components of the given type into a slice.
+func (l *entityLoader) decodeColumn(tp reflect.Type, jsonData []byte) (reflect.Value, error) {
+	values := reflect.New(reflect.SliceOf(tp))
+
+	_, hasMigrations := l.opts.migrations[tp]
+	_, hasCodec := l.opts.codecs[tp]
+	if !hasCodec && (!hasMigrations || l.schema >= l.opts.schemaVersion) {
+		if err := json.Unmarshal(jsonData, values.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return values.Elem(), nil
+	}
+
+	// With migrations or a codec, elements need to be decoded one by one.
+	elements := []entry{}
+	if err := json.Unmarshal(jsonData, &elements); err != nil {
+		return reflect.Value{}, err
+	}
+	slice := reflect.MakeSlice(reflect.SliceOf(tp), len(elements), len(elements))
+	for i, elem := range elements {
+		compData, err := migrate(tp, l.schema, elem.Bytes, l.opts)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if err := decodeValue(tp, compData, slice.Index(i).Addr().Interface(), l.opts); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return slice, nil
+}
+
+// checkArchetypes checks that the archetypes contain all alive entities.
+func (l *entityLoader) checkArchetypes() error {
+	count := 0
+	for _, loaded := range l.loaded {
+		if loaded {
+			count++
+		}
+	}
+	if count != len(l.entities.Alive) {
+		return countError("Archetypes", count, len(l.entities.Alive))
+	}
+	return nil
+}
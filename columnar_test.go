@@ -0,0 +1,290 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func countEntities[T any](w *ecs.World) int {
+	query := ecs.NewFilter1[T](w).Query()
+	defer query.Close()
+	return query.Count()
+}
+
+func countAll(w *ecs.World) int {
+	query := ecs.NewUnsafeFilter(w).Query()
+	defer query.Close()
+	return query.Count()
+}
+
+func TestSerializeColumnar(t *testing.T) {
+	w := createArchetypesWorld()
+
+	jsonRows, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	fmt.Println(string(jsonData))
+
+	assert.Less(t, len(jsonData), len(jsonRows))
+	assert.NotContains(t, string(jsonData), `"Components"`)
+
+	w2 := createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+
+	w2 = createWorld(true)
+	err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w2)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+
+	jsonData2, err := arkserde.Serialize(w2, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	assert.Equal(t, string(jsonData), string(jsonData2))
+}
+
+func TestSerializeColumnarOutput(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	posMap := ecs.NewMap1[Position](w)
+	relMap := ecs.NewMap2[Position, ChildRelation](w)
+
+	parent := posMap.NewEntity(&Position{X: 1, Y: 2})
+	relMap.NewEntity(&Position{X: 3, Y: 4}, &ChildRelation{Dummy: 5}, ecs.RelIdx(1, parent))
+	relMap.NewEntity(&Position{X: 6, Y: 7}, &ChildRelation{Dummy: 8}, ecs.RelIdx(1, parent))
+	w.NewEntity()
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+
+	expected := `"Archetypes" : [
+  {
+    "Entities" : [0],
+    "Targets" : {},
+    "Columns" : {}
+  },
+  {
+    "Entities" : [1],
+    "Targets" : {},
+    "Columns" : {
+      "arkserde_test.Position" : [{"X":1,"Y":2}]
+    }
+  },
+  {
+    "Entities" : [2,3],
+    "Targets" : {
+      "arkserde_test.ChildRelation" : [2,0]
+    },
+    "Columns" : {
+      "arkserde_test.Position" : [{"X":3,"Y":4},{"X":6,"Y":7}],
+      "arkserde_test.ChildRelation" : [{"Dummy":5},{"Dummy":8}]
+    }
+  }
+],`
+	assert.Contains(t, string(jsonData), expected)
+}
+
+func TestSerializeColumnarSkip(t *testing.T) {
+	w := createArchetypesWorld()
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Columnar(), arkserde.Opts.SkipComponents(ecs.C[Velocity]()))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(jsonData), `arkserde_test.Velocity`)
+
+	w2 := createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+	assert.Equal(t, countEntities[Position](w), countEntities[Position](w2))
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+
+	w2 = createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2, arkserde.Opts.SkipComponents(ecs.C[Velocity](), ecs.C[ChildRelation]()))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+	assert.Equal(t, 0, countEntities[ChildRelation](w2))
+	assert.Equal(t, countEntities[Position](w), countEntities[Position](w2))
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.Columnar(), arkserde.Opts.SkipAllComponents())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(jsonData), `"Entities" :`))
+
+	w2 = createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, countAll(w), countAll(w2))
+	assert.Equal(t, 0, countEntities[Position](w2))
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.Columnar(), arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+	assert.Contains(t, string(jsonData), `"Archetypes" : []`)
+
+	w2 = createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countAll(w2))
+}
+
+func TestDeserializeColumnarMigrate(t *testing.T) {
+	jsonData := strings.ReplaceAll(textColumnar, `"X":`, `"PosX":`)
+	renameFields := func(jsonData []byte) ([]byte, error) {
+		return []byte(strings.ReplaceAll(string(jsonData), "PosX", "X")), nil
+	}
+
+	w := createWorld(true)
+	posMap := ecs.NewMap[Position](w)
+	err := arkserde.Deserialize([]byte(jsonData), w,
+		arkserde.Opts.SchemaVersion(1),
+		arkserde.Opts.Migrate(ecs.C[Position](), 0, renameFields))
+	assert.Nil(t, err)
+
+	query := ecs.NewUnsafeFilter(w).Query()
+	query.Next()
+	assert.Equal(t, Position{X: 1, Y: 2}, *posMap.Get(query.Entity()))
+	query.Next()
+	assert.Equal(t, Position{X: 3, Y: 4}, *posMap.Get(query.Entity()))
+	query.Close()
+}
+
+func TestDeserializeColumnarErrors(t *testing.T) {
+	deserialize := func(text string) []error {
+		return []error{
+			arkserde.Deserialize([]byte(text), createWorld(true)),
+			arkserde.DeserializeFrom(bytes.NewReader([]byte(text)), createWorld(true)),
+		}
+	}
+
+	for _, err := range deserialize(textColumnar) {
+		assert.Nil(t, err)
+	}
+
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{strings.Replace(textColumnar, `"Entities" : [1]`, `"Entities" : [5]`, 1), "entity index 5 out of range for 2 alive entities"},
+		{strings.Replace(textColumnar, `"Entities" : [1]`, `"Entities" : [0]`, 1), "entity index 0 appears in more than one archetype"},
+		{strings.Replace(textColumnar, `[3,0]],"Alive":[2,3]`, `[3,0],[4,0]],"Alive":[2,3,4]`, 1), "found components for 2 entities, but world has 3 alive entities"},
+		{strings.Replace(textColumnar, `"arkserde_test.ChildRelation" : [2,0]`, ``, 1), "arkserde_test.ChildRelation: missing relation target"},
+		{strings.Replace(textColumnar, `[{"Dummy":5}]`, `[{"Dummy":5},{"Dummy":6}]`, 1), "arkserde_test.ChildRelation: found 2 values, but archetype has 1 entities"},
+		{strings.Replace(textColumnar, `"arkserde_test.ChildRelation" : [{`, `"arkserde_test.Unknown" : [{`, 1), "arkserde_test.Unknown: component type is not registered"},
+		{strings.Replace(textColumnar, `[{"Dummy":5}]`, `{"Dummy":5}`, 1), "json: slice unexpected end of JSON input"},
+		{strings.Replace(textColumnar, `"Archetypes" : [`, `"Components" : [{}, {}], "Archetypes" : [`, 1), "found both sections Components and Archetypes"},
+	}
+
+	for _, tt := range tests {
+		for _, err := range deserialize(tt.text) {
+			if assert.NotNil(t, err, tt.err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		}
+	}
+}
+
+const textColumnar = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
+	"Types" : [
+	  "arkserde_test.Position",
+	  "arkserde_test.ChildRelation"
+	],
+	"Archetypes" : [
+	  {
+		"Entities" : [0],
+		"Targets" : {},
+		"Columns" : {
+		  "arkserde_test.Position" : [{"X":1,"Y":2}]
+		}
+	  },
+	  {
+		"Entities" : [1],
+		"Targets" : {
+		  "arkserde_test.ChildRelation" : [2,0]
+		},
+		"Columns" : {
+		  "arkserde_test.Position" : [{"X":3,"Y":4}],
+		  "arkserde_test.ChildRelation" : [{"Dummy":5}]
+		}
+	  }
+	],
+	"Resources" : {}}`
+
+func benchmarkSerializeColumnar(n int, b *testing.B) {
+	w := ecs.NewWorld(1024)
+
+	mapper := ecs.NewMap2[Position, Velocity](w)
+	mapper.NewBatchFn(n, nil)
+
+	for b.Loop() {
+		_, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+		if err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+func BenchmarkSerializeColumnar_100(b *testing.B) {
+	benchmarkSerializeColumnar(100, b)
+}
+
+func BenchmarkSerializeColumnar_1000(b *testing.B) {
+	benchmarkSerializeColumnar(1000, b)
+}
+
+func BenchmarkSerializeColumnar_10000(b *testing.B) {
+	benchmarkSerializeColumnar(10000, b)
+}
+
+func BenchmarkSerializeColumnar_100000(b *testing.B) {
+	benchmarkSerializeColumnar(100000, b)
+}
+
+func benchmarkDeserializeColumnar(n int, b *testing.B) {
+	w := ecs.NewWorld(1024)
+
+	mapper := ecs.NewMap2[Position, Velocity](w)
+	mapper.NewBatchFn(n, nil)
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	_ = ecs.ComponentID[Velocity](w2)
+
+	for b.Loop() {
+		err = arkserde.Deserialize(jsonData, w2)
+		if err != nil {
+			panic(err.Error())
+		}
+		b.StopTimer()
+		w2.Reset()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkDeserializeColumnar_100(b *testing.B) {
+	benchmarkDeserializeColumnar(100, b)
+}
+
+func BenchmarkDeserializeColumnar_1000(b *testing.B) {
+	benchmarkDeserializeColumnar(1000, b)
+}
+
+func BenchmarkDeserializeColumnar_10000(b *testing.B) {
+	benchmarkDeserializeColumnar(10000, b)
+}
+
+func BenchmarkDeserializeColumnar_100000(b *testing.B) {
+	benchmarkDeserializeColumnar(100000, b)
+}
@@ -0,0 +1,63 @@
+package arkserde
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/goccy/go-json"
+)
+
+// streamDecoder is a thin wrapper around a [json.Decoder]
+// for token-level decoding of serialized worlds.
+type streamDecoder struct {
+	*json.Decoder
+}
+
+func newStreamDecoder(r io.Reader) streamDecoder {
+	return streamDecoder{json.NewDecoder(r)}
+}
+
+// expectDelim reads the next token and checks that it is the given delimiter.
+func (d streamDecoder) expectDelim(delim json.Delim) error {
+	token, err := d.Token()
+	if err != nil {
+		return err
+	}
+	if del, ok := token.(json.Delim); !ok || del != delim {
+		return fmt.Errorf("expected %s, got %v", delim, token)
+	}
+	return nil
+}
+
+// key reads the next object key.
+func (d streamDecoder) key() (string, error) {
+	token, err := d.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("expected object key, got %v", token)
+	}
+	return key, nil
+}
+
+// array reads a JSON array, and calls fn for each element.
+// The function is responsible for decoding the element.
+func (d streamDecoder) array(fn func(i int) error) error {
+	if err := d.expectDelim('['); err != nil {
+		return err
+	}
+	for i := 0; d.More(); i++ {
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return d.expectDelim(']')
+}
+
+// skip skips the next value.
+func (d streamDecoder) skip() error {
+	value := entry{}
+	return d.Decode(&value)
+}
@@ -0,0 +1,392 @@
+package arkserde
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// SerializeDelta serializes the changes of an Ark [ecs.World] compared to a baseline.
+//
+// The baseline must be data created by [Serialize] or [SerializeTo], in any layout.
+// The delta contains the current entity pool, the components of added entities,
+// changed component values, added and removed components, and changed resources.
+// Unchanged entities and resources are omitted.
+//
+// Use [ApplyDelta] to restore the world's state from the baseline and the delta.
+//
+// Supports the same options as [Serialize], except [Options.Columnar].
+// With [Options.Compress], the baseline is expected to be compressed, and the delta is compressed too.
+func SerializeDelta(baseline []byte, world *ecs.World, options ...Option) ([]byte, error) {
+	opts := newSerdeOptions(options...)
+
+	if opts.compressed {
+		var err error
+		baseline, err = uncompressGZip(baseline)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	if opts.compressed {
+		err := writeGZip(&buffer, opts.compressionLevel, func(w io.Writer) error {
+			return serializeDelta(w, baseline, world, &opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	if err := serializeDelta(&buffer, baseline, world, &opts); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ApplyDelta deserializes a world from a baseline and a delta created by [SerializeDelta].
+//
+// Returns an error if the delta was created for a different baseline.
+// The world must be prepared like for [Deserialize], and the same options are supported.
+// With [Options.Compress], both baseline and delta are expected to be compressed.
+//
+// Errors in the data are returned as [*DecodeError], like for [Deserialize].
+// Errors of the baseline as a whole are reported for section "Baseline".
+func ApplyDelta(baseline, delta []byte, world *ecs.World, options ...Option) error {
+	opts := newSerdeOptions(options...)
+
+	if opts.compressed {
+		var err error
+		if baseline, err = uncompressGZip(baseline); err != nil {
+			return newDecodeError("Baseline", -1, "", err)
+		}
+		if delta, err = uncompressGZip(delta); err != nil {
+			return newDecodeError("", -1, "", err)
+		}
+	}
+
+	base := deserializer{}
+	if err := json.Unmarshal(baseline, &base); err != nil {
+		return newDecodeError("Baseline", -1, "", err)
+	}
+	deltaData := deltaDeserializer{}
+	if err := json.Unmarshal(delta, &deltaData); err != nil {
+		return newDecodeError("", -1, "", err)
+	}
+	if deltaData.Baseline != baselineHash(baseline) {
+		return newDecodeError("Baseline", -1, "", fmt.Errorf("delta was not created for this baseline"))
+	}
+	if err := checkVersion(&deltaData.Version, &opts); err != nil {
+		return newDecodeError("Version", -1, "", err)
+	}
+	if base.Version.Schema != deltaData.Version.Schema {
+		return newDecodeError("Version", -1, "", fmt.Errorf("baseline has schema version %d, but delta has schema version %d", base.Version.Schema, deltaData.Version.Schema))
+	}
+
+	deserial, err := applyDelta(&base, &deltaData)
+	if err != nil {
+		return err
+	}
+
+	return deserializeData(world, deserial, &opts, nil)
+}
+
+type deltaDeserializer struct {
+	Version   version
+	Baseline  string
+	World     *ecs.EntityDump
+	Types     []string
+	Entities  []entityDelta
+	Resources map[string]entry
+}
+
+// entityDelta contains the changes of a single entity.
+type entityDelta struct {
+	Entity ecs.Entity
+	Set    map[string]entry
+	Remove []string
+}
+
+func serializeDelta(w io.Writer, baseline []byte, world *ecs.World, opts *serdeOptions) error {
+	base := deserializer{}
+	if err := json.Unmarshal(baseline, &base); err != nil {
+		return err
+	}
+	baseEntities, err := entityComponents(&base)
+	if err != nil {
+		return err
+	}
+
+	// The current state is compared in serialized form, so that values are encoded exactly like in the baseline.
+	currentOpts := *opts
+	currentOpts.compressed = false
+	currentOpts.columnar = false
+	buffer := bytes.Buffer{}
+	if err := serializeTo(&buffer, world, &currentOpts); err != nil {
+		return err
+	}
+	current := deserializer{}
+	if err := json.Unmarshal(buffer.Bytes(), &current); err != nil {
+		return err
+	}
+	currentEntities, err := entityComponents(&current)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriterSize(w, writeBufferSize)
+
+	writer.WriteString("{\n")
+
+	serializeVersion(writer, opts)
+	writer.WriteString(",\n")
+
+	writer.WriteString("\"Baseline\" : \"")
+	writer.WriteString(baselineHash(baseline))
+	writer.WriteString("\",\n")
+
+	if !opts.skipEntities {
+		if err := serializeWorld(&current.World, writer, opts); err != nil {
+			return err
+		}
+		writer.WriteString(",\n")
+
+		typesJSON, err := json.Marshal(current.Types)
+		if err != nil {
+			return err
+		}
+		writer.WriteString("\"Types\" : ")
+		writer.Write(typesJSON)
+		writer.WriteString(",\n")
+
+		writer.WriteString("\"Entities\" : [")
+		count := 0
+		for _, idx := range current.World.Alive {
+			entity := current.World.Entities[idx]
+			comps := currentEntities[entity]
+			baseComps, existed := baseEntities[entity]
+
+			set := []string{}
+			for _, name := range sortedKeys(comps) {
+				if baseValue, ok := baseComps[name]; !ok || !bytes.Equal(baseValue.Bytes, comps[name].Bytes) {
+					set = append(set, name)
+				}
+			}
+			remove := []string{}
+			for _, name := range sortedKeys(baseComps) {
+				if _, ok := comps[name]; !ok {
+					remove = append(remove, name)
+				}
+			}
+			if existed && len(set) == 0 && len(remove) == 0 {
+				continue
+			}
+
+			if count > 0 {
+				writer.WriteString(",")
+			}
+			count++
+
+			eJSON, err := entity.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			writer.WriteString("\n  {\"Entity\" : ")
+			writer.Write(eJSON)
+			writer.WriteString(", \"Set\" : {")
+			for i, name := range set {
+				if i > 0 {
+					writer.WriteString(", ")
+				}
+				writer.WriteString(strconv.Quote(name))
+				writer.WriteString(" : ")
+				writer.Write(comps[name].Bytes)
+			}
+			writer.WriteString("}")
+			if len(remove) > 0 {
+				removeJSON, err := json.Marshal(remove)
+				if err != nil {
+					return err
+				}
+				writer.WriteString(", \"Remove\" : ")
+				writer.Write(removeJSON)
+			}
+			writer.WriteString("}")
+		}
+		if count > 0 {
+			writer.WriteString("\n")
+		}
+		writer.WriteString("],\n")
+	}
+
+	writer.WriteString("\"Resources\" : {")
+	count := 0
+	for _, name := range sortedKeys(current.Resources) {
+		res := current.Resources[name]
+		if baseRes, ok := base.Resources[name]; ok && bytes.Equal(baseRes.Bytes, res.Bytes) {
+			continue
+		}
+		if count > 0 {
+			writer.WriteString(",")
+		}
+		count++
+		writer.WriteString("\n    ")
+		writer.WriteString(strconv.Quote(name))
+		writer.WriteString(" : ")
+		writer.Write(res.Bytes)
+	}
+	if count > 0 {
+		writer.WriteString("\n")
+	}
+	writer.WriteString("}\n")
+	writer.WriteString("}\n")
+
+	return writer.Flush()
+}
+
+// applyDelta applies a delta to a baseline, and returns the resulting data in the per-entity layout.
+func applyDelta(base *deserializer, delta *deltaDeserializer) (*deserializer, error) {
+	result := deserializer{
+		Version:   delta.Version,
+		World:     base.World,
+		Types:     base.Types,
+		Resources: map[string]entry{},
+	}
+	for name, res := range base.Resources {
+		result.Resources[name] = res
+	}
+	for name, res := range delta.Resources {
+		result.Resources[name] = res
+	}
+
+	if delta.World == nil {
+		// Entities are unchanged.
+		result.Components = base.Components
+		result.Archetypes = base.Archetypes
+		return &result, nil
+	}
+	result.World = *delta.World
+	result.Types = delta.Types
+
+	entities, err := entityComponents(base)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range delta.Entities {
+		comps, ok := entities[change.Entity]
+		if !ok {
+			comps = map[string]entry{}
+			entities[change.Entity] = comps
+		}
+		for name, value := range change.Set {
+			comps[name] = value
+		}
+		for _, name := range change.Remove {
+			delete(comps, name)
+		}
+	}
+
+	if errs := checkEntities(&result.World); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	result.Components = make([]entry, len(result.World.Alive))
+	for i, idx := range result.World.Alive {
+		entity := result.World.Entities[idx]
+		comps, ok := entities[entity]
+		if !ok {
+			return nil, &DecodeError{Section: "World", Index: i, Entity: entity, Err: fmt.Errorf("found no components in baseline or delta")}
+		}
+		jsonData, err := json.Marshal(comps)
+		if err != nil {
+			return nil, &DecodeError{Section: "Components", Index: i, Entity: entity, Err: err}
+		}
+		result.Components[i] = entry{Bytes: jsonData}
+	}
+	return &result, nil
+}
+
+// entityComponents returns the serialized components of all entities, by entity and type name.
+// Relation targets are included under the type name with the target tag.
+// Works for both layouts. Errors in the data are returned as [*DecodeError].
+func entityComponents(deserial *deserializer) (map[ecs.Entity]map[string]entry, error) {
+	dump := &deserial.World
+	result := make(map[ecs.Entity]map[string]entry, len(dump.Alive))
+
+	entityAt := func(index int) (ecs.Entity, error) {
+		entity := serializedEntity(dump, index)
+		if entity.IsZero() {
+			return entity, fmt.Errorf("entity index %d out of range for %d alive entities", index, len(dump.Alive))
+		}
+		return entity, nil
+	}
+
+	for i, comps := range deserial.Components {
+		entity, err := entityAt(i)
+		if err != nil {
+			return nil, newDecodeError("Components", i, "", err)
+		}
+		mp := map[string]entry{}
+		if err := json.Unmarshal(comps.Bytes, &mp); err != nil {
+			return nil, &DecodeError{Section: "Components", Index: i, Entity: entity, Err: err}
+		}
+		result[entity] = mp
+	}
+
+	for a, arch := range deserial.Archetypes {
+		maps := make([]map[string]entry, len(arch.Entities))
+		for i, idx := range arch.Entities {
+			entity, err := entityAt(idx)
+			if err != nil {
+				return nil, newDecodeError("Archetypes", a, "", err)
+			}
+			maps[i] = map[string]entry{}
+			result[entity] = maps[i]
+		}
+		for name, target := range arch.Targets {
+			targetJSON, err := target.MarshalJSON()
+			if err != nil {
+				return nil, newDecodeError("Archetypes", a, name, err)
+			}
+			for _, mp := range maps {
+				mp[name+targetTag] = entry{Bytes: targetJSON}
+			}
+		}
+		for name, column := range arch.Columns {
+			values := []entry{}
+			if err := json.Unmarshal(column.Bytes, &values); err != nil {
+				return nil, newDecodeError("Archetypes", a, name, err)
+			}
+			if len(values) != len(maps) {
+				return nil, newDecodeError("Archetypes", a, name, fmt.Errorf("found %d values, but archetype has %d entities", len(values), len(maps)))
+			}
+			for i, mp := range maps {
+				mp[name] = values[i]
+			}
+		}
+	}
+	return result, nil
+}
+
+// baselineHash returns a hash of the baseline data, to verify that a delta is applied to the right baseline.
+func baselineHash(baseline []byte) string {
+	hash := fnv.New64a()
+	hash.Write(baseline)
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](mp map[string]V) []string {
+	keys := make([]string, 0, len(mp))
+	for key := range mp {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
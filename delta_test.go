@@ -0,0 +1,141 @@
+package arkserde_test
+
+import (
+	"strings"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+// modifyArchetypesWorld applies some changes to a world created by createArchetypesWorld.
+func modifyArchetypesWorld(w *ecs.World) {
+	posMap := ecs.NewMap[Position](w)
+	velMap := ecs.NewMap[Velocity](w)
+	relMap := ecs.NewMap[ChildRelation](w)
+
+	query := ecs.NewFilter2[Position, Velocity](w).Query()
+	entities := []ecs.Entity{}
+	for query.Next() {
+		entities = append(entities, query.Entity())
+	}
+	// Changed component value
+	posMap.Get(entities[0]).X = 100
+	// Removed component
+	velMap.Remove(entities[1])
+	// Removed entity
+	w.RemoveEntity(entities[2])
+
+	// Added entity
+	parent := posMap.NewEntity(&Position{X: 200})
+	// Added component, and changed relation target
+	query2 := ecs.NewFilter1[ChildRelation](w).Query()
+	relEntities := []ecs.Entity{}
+	for query2.Next() {
+		relEntities = append(relEntities, query2.Entity())
+	}
+	velMap.Add(relEntities[0], &Velocity{X: 300})
+	relMap.SetRelation(relEntities[1], parent)
+
+	*ecs.GetResource[Velocity](w) = Velocity{X: 5}
+}
+
+func TestSerializeDelta(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		w := createArchetypesWorld()
+		ecs.AddResource(w, &Velocity{X: 1})
+		ecs.AddResource(w, &Position{X: 1})
+
+		baseline, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		// Without changes
+		delta, err := arkserde.SerializeDelta(baseline, w)
+		assert.Nil(t, err)
+		assert.Contains(t, string(delta), "\"Entities\" : [],")
+		assert.Contains(t, string(delta), "\"Resources\" : {}")
+
+		modifyArchetypesWorld(w)
+
+		delta, err = arkserde.SerializeDelta(baseline, w)
+		assert.Nil(t, err)
+
+		full, err := arkserde.Serialize(w)
+		assert.Nil(t, err)
+		assert.Less(t, len(delta), len(full))
+		assert.Equal(t, 5, strings.Count(string(delta), "\"Entity\" : "))
+		assert.Contains(t, string(delta), "\"Remove\" : [\"arkserde_test.Velocity\"]")
+
+		w2 := newWorldLike(w)
+
+		err = arkserde.ApplyDelta(baseline, delta, w2)
+		assert.Nil(t, err)
+
+		assertEqualWorlds(t, w, w2)
+		assert.Equal(t, Velocity{X: 5}, *ecs.GetResource[Velocity](w2))
+		assert.Equal(t, Position{X: 1}, *ecs.GetResource[Position](w2))
+	}
+}
+
+func TestSerializeDeltaOptions(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.AddResource(w, &Velocity{X: 1})
+
+	baseline, err := arkserde.Serialize(w, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	modifyArchetypesWorld(w)
+
+	delta, err := arkserde.SerializeDelta(baseline, w, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.ApplyDelta(baseline, delta, w2, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+
+	baseline, err = arkserde.Serialize(w)
+	assert.Nil(t, err)
+	*ecs.GetResource[Velocity](w) = Velocity{X: 10}
+
+	delta, err = arkserde.SerializeDelta(baseline, w, arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+	assert.NotContains(t, string(delta), "\"Entities\"")
+
+	w2 = newWorldLike(w)
+	err = arkserde.ApplyDelta(baseline, delta, w2)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+	assert.Equal(t, Velocity{X: 10}, *ecs.GetResource[Velocity](w2))
+}
+
+func TestApplyDeltaErrors(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.AddResource(w, &Velocity{X: 1})
+
+	baseline, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	modifyArchetypesWorld(w)
+	delta, err := arkserde.SerializeDelta(baseline, w)
+	assert.Nil(t, err)
+
+	other, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	err = arkserde.ApplyDelta(other, delta, newWorldLike(w))
+	assert.EqualError(t, err, "Baseline: delta was not created for this baseline")
+
+	err = arkserde.ApplyDelta(baseline, delta, newWorldLike(w), arkserde.Opts.Compress())
+	assert.NotNil(t, err)
+
+	err = arkserde.ApplyDelta(baseline, delta[:len(delta)/2], newWorldLike(w))
+	assert.NotNil(t, err)
+
+	_, err = arkserde.SerializeDelta([]byte("{"), w)
+	assert.NotNil(t, err)
+}
@@ -2,8 +2,8 @@ package arkserde
 
 import (
 	"fmt"
+	"io"
 	"reflect"
-	"slices"
 	"strings"
 
 	"github.com/goccy/go-json"
@@ -14,14 +14,27 @@ import (
 //
 // The world must be prepared the following way:
 //   - The world must not contain any alive or dead entities (i.e. a new or [ecs.World.Reset] world)
-//   - All required component types must be registered using [ecs.ComponentID]
-//   - All required resources must be added as dummies using [ecs.AddResource]
+//   - All required component types must be registered using [ecs.ComponentID], or globally using [Register]
+//   - All required resources must be added as dummies using [ecs.AddResource], or globally using [RegisterResource]
 //
 // The options can be used to skip some or all components,
 // entities entirely, and/or some or all resources.
 // It only some components or resources are skipped,
 // they still need to be registered to the world.
 //
+// Data in the columnar layout (see [Options.Columnar]) is detected automatically.
+//
+// Data written in a newer format version, or with a newer schema version than
+// set by [Options.SchemaVersion], results in an error.
+// Data with an older schema version is migrated, see [Options.Migrate].
+//
+// All data is decoded and checked before it is added to the world.
+// On error, the world is left unchanged, except for types registered from the global registry.
+// Errors in the data are returned as [*DecodeError], with the section, entity and type where they occurred.
+//
+// After all data was added, the hooks of resources and components are called,
+// followed by the callbacks of [Options.OnLoaded]. See [AfterDeserializer].
+//
 // # Query iteration order
 //
 // After deserialization, it is not guaranteed that entity iteration order in queries is the same as before.
@@ -35,125 +48,695 @@ func Deserialize(jsonData []byte, world *ecs.World, options ...Option) error {
 		var err error
 		jsonData, err = uncompressGZip(jsonData)
 		if err != nil {
-			return err
+			return newDecodeError("", -1, "", err)
 		}
 	}
 
 	deserial := deserializer{}
 	if err := json.Unmarshal(jsonData, &deserial); err != nil {
-		return err
+		return newDecodeError("", -1, "", err)
+	}
+
+	if err := checkVersion(&deserial.Version, &opts); err != nil {
+		return newDecodeError("Version", -1, "", err)
+	}
+
+	return deserializeData(world, &deserial, &opts, nil)
+}
+
+// DeserializeFrom deserializes an Ark [ecs.World] from JSON, read from the given [io.Reader].
+//
+// In contrast to [Deserialize], the JSON document is decoded incrementally, one entity at a time,
+// and never held in memory as a whole.
+// Use it to load large worlds directly from a file or a network connection.
+// With option [Options.Compress], the input is decompressed on the fly.
+//
+// The sections of the document are expected in the order written by [Serialize] and [SerializeTo].
+// Particularly, "World" and "Types" must precede "Components".
+//
+// As entities are added to the world while reading, the world may be partially loaded on error.
+//
+// See [Deserialize] for how the world must be prepared, and for further details.
+func DeserializeFrom(r io.Reader, world *ecs.World, options ...Option) error {
+	opts := newSerdeOptions(options...)
+
+	if opts.compressed {
+		return readGZip(r, func(r io.Reader) error {
+			return deserializeFrom(r, world, &opts)
+		})
+	}
+	return deserializeFrom(r, world, &opts)
+}
+
+func deserializeFrom(r io.Reader, world *ecs.World, opts *serdeOptions) error {
+	dec := newStreamDecoder(r)
+
+	if err := dec.expectDelim('{'); err != nil {
+		return newDecodeError("", -1, "", err)
+	}
+
+	var entities *ecs.EntityDump
+	var loader *entityLoader
+	var resources []interface{}
+	versionInfo := version{}
+	hasComponents := false
+	// Version must be the first section, if present.
+	isFirst := true
+
+	// prepareEntities checks the types and loads the entities into the world.
+	// It is called with the "Types" section, or at the latest before "Components".
+	prepareEntities := func(types []string) error {
+		if entities == nil {
+			entities = &ecs.EntityDump{}
+		}
+		if err := registerComponents(world, types, opts); err != nil {
+			return err
+		}
+		var err error
+		loader, err = newEntityLoader(world, entities, versionInfo.Schema, opts, nil)
+		if err != nil {
+			return err
+		}
+		if err := loader.checkTypes(types); err != nil {
+			return err
+		}
+		loader.loadEntities()
+		loader.preserveTypes()
+		return nil
+	}
+
+	for dec.More() {
+		key, err := dec.key()
+		if err != nil {
+			return newDecodeError("", -1, "", err)
+		}
+
+		first := isFirst
+		isFirst = false
+
+		switch key {
+		case "Version":
+			if !first {
+				return newDecodeError("", -1, "", fmt.Errorf("section Version must be the first section"))
+			}
+			if err := dec.Decode(&versionInfo); err != nil {
+				return newDecodeError("Version", -1, "", err)
+			}
+			if err := checkVersion(&versionInfo, opts); err != nil {
+				return newDecodeError("Version", -1, "", err)
+			}
+		case "World":
+			if loader != nil {
+				return newDecodeError("", -1, "", fmt.Errorf("section World must precede sections Types and Components"))
+			}
+			entities = &ecs.EntityDump{}
+			if err := dec.Decode(entities); err != nil {
+				return newDecodeError("World", -1, "", err)
+			}
+		case "Types":
+			types := []string{}
+			if err := dec.Decode(&types); err != nil {
+				return newDecodeError("Types", -1, "", err)
+			}
+			if opts.skipEntities {
+				continue
+			}
+			if loader != nil {
+				return newDecodeError("", -1, "", fmt.Errorf("section Types must precede section Components"))
+			}
+			if err := prepareEntities(types); err != nil {
+				return err
+			}
+		case "Components", "Archetypes":
+			if opts.skipEntities {
+				if err := dec.array(func(int) error { return dec.skip() }); err != nil {
+					return newDecodeError(key, -1, "", err)
+				}
+				continue
+			}
+			if loader == nil {
+				if err := prepareEntities(nil); err != nil {
+					return err
+				}
+			}
+			if hasComponents {
+				return newDecodeError(key, -1, "", fmt.Errorf("found both sections Components and Archetypes"))
+			}
+			hasComponents = true
+			if key == "Archetypes" {
+				err := dec.array(func(i int) error {
+					arch := archetype{}
+					if err := dec.Decode(&arch); err != nil {
+						return newDecodeError("Archetypes", i, "", err)
+					}
+					return loader.loadArchetype(i, &arch)
+				})
+				if err != nil {
+					return wrapDecodeError(key, err)
+				}
+				if err := loader.checkArchetypes(); err != nil {
+					return err
+				}
+				continue
+			}
+			count := 0
+			err := dec.array(func(i int) error {
+				if i >= len(entities.Alive) {
+					return newDecodeError("Components", -1, "", fmt.Errorf("found components for more than %d entities, but world has %d alive entities", i, len(entities.Alive)))
+				}
+				comps := entry{}
+				if err := dec.Decode(&comps); err != nil {
+					return loader.entityError(i, "", err)
+				}
+				count++
+				return loader.load(i, comps.Bytes)
+			})
+			if err != nil {
+				return wrapDecodeError(key, err)
+			}
+			if count != len(entities.Alive) {
+				return countError("Components", count, len(entities.Alive))
+			}
+		case "Resources":
+			res := map[string]entry{}
+			if err := dec.Decode(&res); err != nil {
+				return newDecodeError("Resources", -1, "", err)
+			}
+			loaded, err := deserializeResources(world, res, versionInfo.Schema, opts, nil)
+			if err != nil {
+				return err
+			}
+			resources = loaded
+		default:
+			if err := dec.skip(); err != nil {
+				return newDecodeError(key, -1, "", err)
+			}
+		}
 	}
 
-	if !opts.skipEntities {
-		world.Unsafe().LoadEntities(&deserial.World)
+	if err := dec.expectDelim('}'); err != nil {
+		return newDecodeError("", -1, "", err)
 	}
+	if entities != nil && !hasComponents && !opts.skipEntities && len(entities.Alive) > 0 {
+		return countError("Components", 0, len(entities.Alive))
+	}
+	return afterLoad(world, loader, resources, opts)
+}
 
-	if err := deserializeComponents(world, &deserial, &opts); err != nil {
+// deserializeData decodes the entities and resources of the data,
+// and adds them to the world only if all of them were decoded successfully.
+func deserializeData(world *ecs.World, deserial *deserializer, opts *serdeOptions, remap *entityRemap) error {
+	comps, err := decodeComponents(world, deserial, opts, remap)
+	if err != nil {
 		return err
 	}
-	if err := deserializeResources(world, &deserial, &opts); err != nil {
+	resources, err := decodeResources(world, deserial.Resources, deserial.Version.Schema, opts)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	comps.apply()
+	resources.apply(world, remap)
+
+	return afterLoad(world, comps.loader, resources.targets, opts)
 }
 
-func deserializeComponents(world *ecs.World, deserial *deserializer, opts *serdeOptions) error {
-	u := world.Unsafe()
+// decodedComponents holds the decoded entities of serialized data, before they are added to the world.
+type decodedComponents struct {
+	loader     *entityLoader // Nil if entities are skipped.
+	entities   []decodedEntity
+	archetypes []decodedArchetype
+}
 
+func decodeComponents(world *ecs.World, deserial *deserializer, opts *serdeOptions, remap *entityRemap) (*decodedComponents, error) {
 	if opts.skipEntities {
-		return nil
+		return &decodedComponents{}, nil
+	}
+
+	if err := registerComponents(world, deserial.Types, opts); err != nil {
+		return nil, err
+	}
+	loader, err := newEntityLoader(world, &deserial.World, deserial.Version.Schema, opts, remap)
+	if err != nil {
+		return nil, err
+	}
+	if err := loader.checkTypes(deserial.Types); err != nil {
+		return nil, err
+	}
+
+	decoded := decodedComponents{loader: loader}
+
+	if deserial.Archetypes != nil {
+		if len(deserial.Components) > 0 {
+			return nil, newDecodeError("Archetypes", -1, "", fmt.Errorf("found both sections Components and Archetypes"))
+		}
+		decoded.archetypes = make([]decodedArchetype, len(deserial.Archetypes))
+		for i := range deserial.Archetypes {
+			if decoded.archetypes[i], err = loader.decodeArchetype(i, &deserial.Archetypes[i]); err != nil {
+				return nil, err
+			}
+		}
+		if err := loader.checkArchetypes(); err != nil {
+			return nil, err
+		}
+		return &decoded, nil
+	}
+
+	if len(deserial.Components) != len(deserial.World.Alive) {
+		return nil, countError("Components", len(deserial.Components), len(deserial.World.Alive))
+	}
+
+	decoded.entities = make([]decodedEntity, len(deserial.Components))
+	if opts.parallel > 1 {
+		err := runChunks(len(deserial.Components), opts.parallel, func(_, start, end int) error {
+			return loader.decodeRange(deserial.Components, decoded.entities, start, end)
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &decoded, nil
 	}
+	if err := loader.decodeRange(deserial.Components, decoded.entities, 0, len(deserial.Components)); err != nil {
+		return nil, err
+	}
+	return &decoded, nil
+}
+
+// decodeRange decodes the components of the entities in the given range of indices into decoded.
+func (l *entityLoader) decodeRange(components []entry, decoded []decodedEntity, start, end int) error {
+	for i := start; i < end; i++ {
+		var err error
+		if decoded[i], err = l.decode(i, components[i].Bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// apply restores the entity pool, and adds the decoded components to the world.
+func (d *decodedComponents) apply() {
+	if d.loader == nil {
+		return
+	}
+	d.loader.loadEntities()
+	d.loader.preserveTypes()
+	for i := range d.entities {
+		d.loader.add(&d.entities[i])
+	}
+	for i := range d.archetypes {
+		d.loader.addArchetype(&d.archetypes[i])
+	}
+}
+
+// entityLoader adds the serialized components to the entities of a world, one entity at a time.
+type entityLoader struct {
+	world          *ecs.World
+	entities       *ecs.EntityDump
+	schema         int
+	opts           *serdeOptions
+	ids            map[string]ecs.ID
+	infos          []ecs.CompInfo
+	skipComponents bitMask
+	loaded         []bool       // Entities loaded from archetypes, by index.
+	alive          []bool       // Alive entities of the dump, by entity ID.
+	unknownTypes   []string     // Names of unknown component types to preserve.
+	remap          *entityRemap // Remapping of entities, for merging. Nil otherwise.
+}
+
+func newEntityLoader(world *ecs.World, entities *ecs.EntityDump, schema int, opts *serdeOptions, remap *entityRemap) (*entityLoader, error) {
+	// The entity pool can only be restored into an empty world. When merging, entities are created instead.
+	if remap == nil {
+		if err := checkEmpty(world); err != nil {
+			return nil, newDecodeError("World", -1, "", err)
+		}
+	}
+	if errs := checkEntities(entities); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return newEntityLoaderUnchecked(world, entities, schema, opts, remap)
+}
+
+// newEntityLoaderUnchecked creates an [entityLoader] without checking the world and the entity pool.
+// Used by [Validate], which reports problems of the entity pool separately.
+func newEntityLoaderUnchecked(world *ecs.World, entities *ecs.EntityDump, schema int, opts *serdeOptions, remap *entityRemap) (*entityLoader, error) {
 	ids := map[string]ecs.ID{}
+	typeIDs := map[reflect.Type]ecs.ID{}
+	uniqueNames := typeNames{}
 	allComps := ecs.ComponentIDs(world)
 	infos := make([]ecs.CompInfo, len(allComps))
 	for _, id := range allComps {
 		if info, ok := ecs.ComponentInfo(world, id); ok {
+			name := typeName(info.Type, opts)
+			if err := uniqueNames.add(name, info.Type, "component"); err != nil {
+				return nil, err
+			}
 			infos[id.Index()] = info
-			ids[info.Type.String()] = id
+			ids[name] = id
+			typeIDs[info.Type] = id
+		}
+	}
+	for _, alias := range opts.aliases {
+		id, ok := typeIDs[alias.tp]
+		if !ok {
+			continue
+		}
+		if err := uniqueNames.add(alias.name, alias.tp, "component"); err != nil {
+			return nil, err
 		}
+		ids[alias.name] = id
 	}
 
-	for _, tp := range deserial.Types {
-		if _, ok := ids[tp]; !ok {
-			return fmt.Errorf("component type is not registered: %s", tp)
+	skipComponents := skipMask(world, opts)
+
+	alive := make([]bool, len(entities.Entities))
+	for _, idx := range entities.Alive {
+		if int(idx) < len(alive) {
+			alive[idx] = true
 		}
 	}
 
-	if len(deserial.Components) != len(deserial.World.Alive) {
-		return fmt.Errorf("found components for %d entities, but world has %d alive entities", len(deserial.Components), len(deserial.World.Alive))
+	return &entityLoader{
+		world:          world,
+		entities:       entities,
+		schema:         schema,
+		opts:           opts,
+		ids:            ids,
+		infos:          infos,
+		skipComponents: skipComponents,
+		loaded:         make([]bool, len(entities.Alive)),
+		alive:          alive,
+		remap:          remap,
+	}, nil
+}
+
+// countError creates the error for a number of entities with components that does not match the number of alive entities.
+func countError(section string, count, alive int) *DecodeError {
+	return newDecodeError(section, -1, "", fmt.Errorf("found components for %d entities, but world has %d alive entities", count, alive))
+}
+
+// checkEmpty checks that the world contains no alive or dead entities.
+func checkEmpty(world *ecs.World) error {
+	current := world.Unsafe().DumpEntities()
+	if len(current.Entities) > reservedEntities || current.Available > 0 {
+		return fmt.Errorf("world must not contain any alive or dead entities")
 	}
+	return nil
+}
 
-	skipComponents := bitMask{}
-	for _, tp := range opts.skipComponents {
-		id := ecs.TypeID(world, tp)
-		skipComponents.Set(id, true)
+// checkEntities checks the consistency of a serialized entity pool.
+// Returns all problems found, for section "World".
+func checkEntities(dump *ecs.EntityDump) []*DecodeError {
+	errs := []*DecodeError{}
+	if len(dump.Entities) > 0 && len(dump.Entities) < reservedEntities {
+		errs = append(errs, newDecodeError("World", -1, "", fmt.Errorf("found %d entities, expected at least %d reserved entities", len(dump.Entities), reservedEntities)))
+	}
+	if len(dump.Alive)+int(dump.Available)+reservedEntities > len(dump.Entities) && len(dump.Entities) > 0 {
+		errs = append(errs, newDecodeError("World", -1, "", fmt.Errorf("found %d alive and %d available entities, but only %d entities", len(dump.Alive), dump.Available, len(dump.Entities))))
+	}
+	if dump.Available > 0 && int(dump.Next) >= len(dump.Entities) {
+		errs = append(errs, newDecodeError("World", -1, "", fmt.Errorf("next entity %d out of range for %d entities", dump.Next, len(dump.Entities))))
 	}
 
-	for i, comps := range deserial.Components {
-		entity := deserial.World.Entities[deserial.World.Alive[i]]
+	entityError := func(index int, err error) *DecodeError {
+		return &DecodeError{Section: "World", Index: index, Entity: serializedEntity(dump, index), Err: err}
+	}
+	seen := make(map[uint32]bool, len(dump.Alive))
+	for i, idx := range dump.Alive {
+		if idx < reservedEntities || int(idx) >= len(dump.Entities) {
+			errs = append(errs, entityError(i, fmt.Errorf("alive entity index %d out of range for %d entities", idx, len(dump.Entities))))
+			continue
+		}
+		if seen[idx] {
+			errs = append(errs, entityError(i, fmt.Errorf("alive entity index %d appears more than once", idx)))
+			continue
+		}
+		seen[idx] = true
+		if entity := dump.Entities[idx]; entity.ID() != idx {
+			errs = append(errs, entityError(i, fmt.Errorf("entity %v found at index %d", entity, idx)))
+		}
+	}
+	return errs
+}
 
-		mp := map[string]entry{}
+// loadEntities restores the serialized entity pool.
+// When merging, creates a new entity for each serialized entity instead.
+func (l *entityLoader) loadEntities() {
+	if l.remap == nil {
+		l.world.Unsafe().LoadEntities(l.entities)
+		return
+	}
+	u := l.world.Unsafe()
+	for _, idx := range l.entities.Alive {
+		l.remap.entities[l.entities.Entities[idx]] = u.NewEntity()
+	}
+}
 
-		if err := json.Unmarshal(comps.Bytes, &mp); err != nil {
-			return err
+// preserveTypes adds the names of unknown component types to the world's [UnknownData],
+// with policy [UnknownPreserve].
+func (l *entityLoader) preserveTypes() {
+	if len(l.unknownTypes) > 0 {
+		preserveUnknown(l.world).addTypes(l.unknownTypes)
+	}
+}
+
+// entity returns the entity for an index in the list of alive entities.
+func (l *entityLoader) entity(index int) ecs.Entity {
+	return l.remap.entity(l.entities.Entities[l.entities.Alive[index]])
+}
+
+// entityError creates a [DecodeError] for the entity at an index in the list of alive entities.
+func (l *entityLoader) entityError(index int, tp string, err error) *DecodeError {
+	return &DecodeError{
+		Section: "Components",
+		Index:   index,
+		Entity:  serializedEntity(l.entities, index),
+		Type:    tp,
+		Err:     err,
+	}
+}
+
+// checkTypes checks that all the given component types are registered.
+// Unknown types are ignored or collected for preserving instead, if the policy for unknown types allows them.
+func (l *entityLoader) checkTypes(types []string) error {
+	for _, tp := range types {
+		if _, ok := l.ids[tp]; ok {
+			continue
 		}
+		switch l.opts.componentPolicy(tp) {
+		case UnknownError:
+			return newDecodeError("Types", -1, tp, fmt.Errorf("component type is not registered"))
+		case UnknownPreserve:
+			l.unknownTypes = append(l.unknownTypes, tp)
+		}
+	}
+	return nil
+}
 
-		// TODO: check that multiple relations work properly
-		idsMap := map[string]ecs.ID{}
-		targetsMap := map[string]ecs.Entity{}
+// isUnknown checks whether a component type name is not registered,
+// and the policy for unknown types allows it.
+func (l *entityLoader) isUnknown(tpName string) bool {
+	_, ok := l.ids[tpName]
+	return !ok && l.opts.componentPolicy(tpName) != UnknownError
+}
 
-		components := make([]component, 0, len(mp))
-		compIDs := make([]ecs.ID, 0, len(mp))
-		for tpName, value := range mp {
-			if strings.HasSuffix(tpName, targetTag) {
-				var target ecs.Entity
-				if err := json.Unmarshal(value.Bytes, &target); err != nil {
-					return err
-				}
-				targetsMap[strings.TrimSuffix(tpName, targetTag)] = target
-				continue
-			}
+// decodedEntity holds the decoded components of an entity, before they are added to the world.
+type decodedEntity struct {
+	index      int               // Index in the list of alive entities.
+	components []component       // Components, with relation targets before remapping.
+	unknown    map[string][]byte // Preserved components of unknown types.
+	// Preserved relation targets of unknown types, by key with the target tag, before remapping.
+	unknownTargets map[string]ecs.Entity
+}
 
-			id := ids[tpName]
-			if skipComponents.Get(id) {
-				continue
-			}
+// load the components of the entity at the given index in the list of alive entities.
+func (l *entityLoader) load(index int, jsonData []byte) error {
+	decoded, err := l.decode(index, jsonData)
+	if err != nil {
+		return err
+	}
+	l.add(&decoded)
+	return nil
+}
 
-			info := infos[id.Index()]
+// decode the components of the entity at the given index in the list of alive entities.
+func (l *entityLoader) decode(index int, jsonData []byte) (decodedEntity, error) {
+	mp := map[string]entry{}
 
-			if info.IsRelation {
-				idsMap[tpName] = id
-			}
+	if err := json.Unmarshal(jsonData, &mp); err != nil {
+		return decodedEntity{}, l.entityError(index, "", err)
+	}
 
-			comp := reflect.New(info.Type).Interface()
-			if err := json.Unmarshal(value.Bytes, &comp); err != nil {
-				return err
+	decoded := decodedEntity{
+		index:      index,
+		components: make([]component, 0, len(mp)),
+	}
+
+	// Components are processed in the order of their names, so that errors are reported deterministically.
+	names := sortedKeys(mp)
+
+	// Relation targets, by the ID of their relation component.
+	targets := map[ecs.ID]ecs.Entity{}
+	for _, tpName := range names {
+		value := mp[tpName]
+		name, ok := strings.CutSuffix(tpName, targetTag)
+		if !ok {
+			continue
+		}
+		if l.isUnknown(name) {
+			if err := l.preserve(&decoded, tpName, value.Bytes); err != nil {
+				return decodedEntity{}, l.entityError(index, name, err)
 			}
-			compIDs = append(compIDs, id)
-			components = append(components, component{
-				ID:   id,
-				Comp: comp,
-			})
+			continue
 		}
+		id, err := l.targetID(name)
+		if err != nil {
+			return decodedEntity{}, l.entityError(index, name, err)
+		}
+		var target ecs.Entity
+		if err := json.Unmarshal(value.Bytes, &target); err != nil {
+			return decodedEntity{}, l.entityError(index, name, err)
+		}
+		targets[id] = target
+	}
 
-		if len(components) == 0 {
+	found := bitMask{}
+	for _, tpName := range names {
+		value := mp[tpName]
+		if strings.HasSuffix(tpName, targetTag) {
 			continue
 		}
 
-		relations := []ecs.Relation{}
-		for name, id := range idsMap {
-			relations = append(relations, ecs.RelID(id, targetsMap[name]))
+		if l.isUnknown(tpName) {
+			if err := l.preserve(&decoded, tpName, value.Bytes); err != nil {
+				return decodedEntity{}, l.entityError(index, tpName, err)
+			}
+			continue
 		}
-		u.AddRel(entity, compIDs, relations...)
-		for _, comp := range components {
-			assign(world, entity, comp.ID, comp.Comp)
+		id, ok, err := l.componentID(tpName, &found)
+		if err != nil {
+			return decodedEntity{}, l.entityError(index, tpName, err)
 		}
+		if !ok {
+			continue
+		}
+
+		info := l.infos[id.Index()]
+
+		compValue, err := decodeComponent(info.Type, l.schema, value.Bytes, l.opts)
+		if err != nil {
+			return decodedEntity{}, l.entityError(index, tpName, err)
+		}
+		comp := component{
+			ID:   id,
+			Comp: compValue.Interface(),
+		}
+		if info.IsRelation {
+			if comp.Target, ok = targets[id]; !ok {
+				return decodedEntity{}, l.entityError(index, tpName, fmt.Errorf("missing relation target"))
+			}
+			if err := l.checkTarget(comp.Target); err != nil {
+				return decodedEntity{}, l.entityError(index, tpName, err)
+			}
+		}
+		decoded.components = append(decoded.components, comp)
+	}
+	return decoded, nil
+}
+
+// preserve keeps the JSON of a component or relation target of an unknown type, with policy [UnknownPreserve].
+func (l *entityLoader) preserve(decoded *decodedEntity, key string, jsonData []byte) error {
+	if l.opts.componentPolicy(strings.TrimSuffix(key, targetTag)) != UnknownPreserve {
+		return nil
+	}
+	if strings.HasSuffix(key, targetTag) {
+		var target ecs.Entity
+		if err := json.Unmarshal(jsonData, &target); err != nil {
+			return err
+		}
+		if decoded.unknownTargets == nil {
+			decoded.unknownTargets = map[string]ecs.Entity{}
+		}
+		decoded.unknownTargets[key] = target
+		return nil
+	}
+	jsonData, err := compactJSON(jsonData)
+	if err != nil {
+		return err
+	}
+	if decoded.unknown == nil {
+		decoded.unknown = map[string][]byte{}
 	}
+	decoded.unknown[key] = jsonData
 	return nil
 }
 
+// componentID returns the ID of a component for its type name, and marks it as found for the current entity or archetype.
+// Returns false for skipped components, and an error if the component was already found, e.g. under an alias.
+func (l *entityLoader) componentID(tpName string, found *bitMask) (ecs.ID, bool, error) {
+	id, ok := l.ids[tpName]
+	if !ok {
+		return ecs.ID{}, false, fmt.Errorf("component type is not registered")
+	}
+	if l.skipComponents.Get(id) {
+		return ecs.ID{}, false, nil
+	}
+	if found.Get(id) {
+		return ecs.ID{}, false, fmt.Errorf("component found more than once")
+	}
+	found.Set(id, true)
+	return id, true, nil
+}
+
+// targetID returns the ID of the relation component for the type name of a relation target.
+func (l *entityLoader) targetID(tpName string) (ecs.ID, error) {
+	id, ok := l.ids[tpName]
+	if !ok {
+		return ecs.ID{}, fmt.Errorf("component type is not registered")
+	}
+	if !l.skipComponents.Get(id) && !l.infos[id.Index()].IsRelation {
+		return ecs.ID{}, fmt.Errorf("found relation target, but type is not a relation")
+	}
+	return id, nil
+}
+
+// checkTarget checks that a relation target is zero or alive in the entity dump.
+// When merging, targets that are not in the data are replaced by the zero entity instead.
+func (l *entityLoader) checkTarget(target ecs.Entity) error {
+	if l.remap != nil || target.IsZero() {
+		return nil
+	}
+	id := int(target.ID())
+	if id >= len(l.alive) || !l.alive[id] || l.entities.Entities[id] != target {
+		return fmt.Errorf("relation target %v is not alive", target)
+	}
+	return nil
+}
+
+// add the decoded components to their entity.
+func (l *entityLoader) add(decoded *decodedEntity) {
+	if len(decoded.unknown) > 0 || len(decoded.unknownTargets) > 0 {
+		preserveUnknown(l.world).addComponents(l.entity(decoded.index), decoded.unknown, decoded.unknownTargets, l.remap)
+	}
+	if len(decoded.components) == 0 {
+		return
+	}
+	entity := l.entity(decoded.index)
+
+	compIDs := make([]ecs.ID, len(decoded.components))
+	relations := []ecs.Relation{}
+	for i, comp := range decoded.components {
+		compIDs[i] = comp.ID
+		if l.infos[comp.ID.Index()].IsRelation {
+			relations = append(relations, ecs.RelID(comp.ID, l.remap.entity(comp.Target)))
+		}
+	}
+	l.world.Unsafe().AddRel(entity, compIDs, relations...)
+	for _, comp := range decoded.components {
+		l.remap.value(reflect.ValueOf(comp.Comp).Elem())
+		assign(l.world, entity, comp.ID, comp.Comp)
+	}
+}
+
 func assign(world *ecs.World, entity ecs.Entity, id ecs.ID, comp interface{}) {
 	dst := world.Unsafe().Get(entity, id)
 	rValue := reflect.ValueOf(comp).Elem()
@@ -163,48 +746,165 @@ func assign(world *ecs.World, entity ecs.Entity, id ecs.ID, comp interface{}) {
 	valuePtr.Elem().Set(rValue)
 }
 
-func deserializeResources(world *ecs.World, deserial *deserializer, opts *serdeOptions) error {
+// deserializeResources decodes the given resources and assigns them to the world's resources.
+// Returns pointers to the assigned resources.
+func deserializeResources(world *ecs.World, resources map[string]entry, schema int, opts *serdeOptions, remap *entityRemap) ([]interface{}, error) {
+	decoded, err := decodeResources(world, resources, schema, opts)
+	if err != nil {
+		return nil, err
+	}
+	decoded.apply(world, remap)
+	return decoded.targets, nil
+}
+
+// decodedResources holds decoded resources, before they are assigned to the world's resources.
+type decodedResources struct {
+	targets []interface{}     // Pointers to the world's resources.
+	values  []reflect.Value   // Pointers to the decoded values.
+	unknown map[string][]byte // Preserved resources of unknown types.
+}
+
+// decodeResources decodes the given resources.
+// Resources added from the global registry are removed again on error.
+func decodeResources(world *ecs.World, resources map[string]entry, schema int, opts *serdeOptions) (*decodedResources, error) {
 	if opts.skipAllResources {
-		return nil
+		return &decodedResources{}, nil
+	}
+
+	added, err := registerResources(world, sortedKeys(resources), opts)
+	if err != nil {
+		return nil, err
+	}
+	decoded, err := decodeRegisteredResources(world, resources, schema, opts)
+	if err != nil {
+		for _, id := range added {
+			world.Resources().Remove(id)
+		}
+		return nil, err
+	}
+	return decoded, nil
+}
+
+func decodeRegisteredResources(world *ecs.World, resources map[string]entry, schema int, opts *serdeOptions) (*decodedResources, error) {
+	loader, err := newResourceLoader(world, opts)
+	if err != nil {
+		return nil, err
 	}
 
+	decoded := decodedResources{}
+	for _, tpName := range sortedKeys(resources) {
+		if _, ok := loader.resIds[tpName]; !ok && opts.resourcePolicy(tpName) != UnknownError {
+			if opts.resourcePolicy(tpName) == UnknownPreserve {
+				value, err := compactJSON(resources[tpName].Bytes)
+				if err != nil {
+					return nil, newDecodeError("Resources", -1, tpName, err)
+				}
+				if decoded.unknown == nil {
+					decoded.unknown = map[string][]byte{}
+				}
+				decoded.unknown[tpName] = value
+			}
+			continue
+		}
+		target, tp, err := loader.resolve(tpName)
+		if err != nil {
+			return nil, newDecodeError("Resources", -1, tpName, err)
+		}
+		if tp == nil {
+			continue
+		}
+
+		value, err := decodeComponent(tp, schema, resources[tpName].Bytes, opts)
+		if err != nil {
+			return nil, newDecodeError("Resources", -1, tpName, err)
+		}
+		decoded.targets = append(decoded.targets, target)
+		decoded.values = append(decoded.values, value)
+	}
+	return &decoded, nil
+}
+
+// apply assigns the decoded values to the world's resources.
+func (d *decodedResources) apply(world *ecs.World, remap *entityRemap) {
+	for i, value := range d.values {
+		remap.value(value.Elem())
+		reflect.ValueOf(d.targets[i]).Elem().Set(value.Elem())
+	}
+	if len(d.unknown) > 0 {
+		data := preserveUnknown(world)
+		for name, value := range d.unknown {
+			data.Resources[name] = value
+		}
+	}
+}
+
+// resourceLoader resolves the names of serialized resources to the resources of a world.
+type resourceLoader struct {
+	world         *ecs.World
+	resTypes      map[ecs.ResID]reflect.Type
+	resIds        map[string]ecs.ResID
+	skipResources bitMask
+}
+
+func newResourceLoader(world *ecs.World, opts *serdeOptions) (*resourceLoader, error) {
 	resTypes := map[ecs.ResID]reflect.Type{}
 	resIds := map[string]ecs.ResID{}
+	typeIDs := map[reflect.Type]ecs.ResID{}
+	uniqueNames := typeNames{}
 	allRes := ecs.ResourceIDs(world)
 	skipResources := bitMask{}
 	for _, id := range allRes {
 		if tp, ok := ecs.ResourceType(world, id); ok {
+			name := typeName(tp, opts)
+			if err := uniqueNames.add(name, tp, "resource"); err != nil {
+				return nil, err
+			}
 			resTypes[id] = tp
-			resIds[tp.String()] = id
+			resIds[name] = id
+			typeIDs[tp] = id
 
-			if slices.Contains(opts.skipResources, tp) {
+			if opts.skipsResource(tp) {
 				skipResources.Set(ecs.ID(id), true)
 			}
 		}
 	}
-
-	for tpName, res := range deserial.Resources {
-		resID, ok := resIds[tpName]
+	for _, alias := range opts.aliases {
+		id, ok := typeIDs[alias.tp]
 		if !ok {
-			return fmt.Errorf("resource type is not registered: %s", tpName)
-		}
-		if skipResources.Get(ecs.ID(resID)) {
 			continue
 		}
+		if err := uniqueNames.add(alias.name, alias.tp, "resource"); err != nil {
+			return nil, err
+		}
+		resIds[alias.name] = id
+	}
 
-		tp := resTypes[resID]
+	return &resourceLoader{
+		world:         world,
+		resTypes:      resTypes,
+		resIds:        resIds,
+		skipResources: skipResources,
+	}, nil
+}
 
-		resLoc := world.Resources().Get(resID)
-		if resLoc == nil {
-			return fmt.Errorf("resource type registered but nil: %s", tpName)
-		}
+// resolve returns a pointer to the resource with the given name, and its type.
+// The type is nil if the resource is skipped.
+func (l *resourceLoader) resolve(tpName string) (interface{}, reflect.Type, error) {
+	resID, ok := l.resIds[tpName]
+	if !ok {
+		return nil, nil, fmt.Errorf("resource type is not registered")
+	}
+	if l.skipResources.Get(ecs.ID(resID)) {
+		return nil, nil, nil
+	}
 
-		ptr := reflect.ValueOf(resLoc).UnsafePointer()
-		value := reflect.NewAt(tp, ptr).Interface()
+	tp := l.resTypes[resID]
 
-		if err := json.Unmarshal(res.Bytes, &value); err != nil {
-			return err
-		}
+	resLoc := l.world.Resources().Get(resID)
+	if resLoc == nil {
+		return nil, nil, fmt.Errorf("resource type registered but nil")
 	}
-	return nil
+
+	ptr := reflect.ValueOf(resLoc).UnsafePointer()
+	return reflect.NewAt(tp, ptr).Interface(), tp, nil
 }
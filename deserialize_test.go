@@ -1,8 +1,11 @@
 package arkserde_test
 
 import (
+	"bytes"
 	"fmt"
 	"math/rand/v2"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -164,6 +167,53 @@ func TestDeserializeSkipResources(t *testing.T) {
 	assert.Equal(t, *res, Velocity{X: 1000})
 }
 
+func TestDeserializeInclude(t *testing.T) {
+	jsonData, parent, child, err := serialize()
+	assert.Nil(t, err)
+
+	for _, load := range []func(data []byte, w *ecs.World, opts ...arkserde.Option) error{
+		arkserde.Deserialize,
+		func(data []byte, w *ecs.World, opts ...arkserde.Option) error {
+			return arkserde.DeserializeFrom(bytes.NewReader(data), w, opts...)
+		},
+	} {
+		// Types that are not included don't need to be registered.
+		w := ecs.NewWorld(1024)
+		posId := ecs.ComponentID[Position](w)
+		_ = ecs.ComponentID[ChildOf](w)
+		ecs.AddResource(w, &Position{})
+
+		err = load(jsonData, w,
+			arkserde.Opts.IncludeComponents(ecs.C[Position]()),
+			arkserde.Opts.IncludeResources(ecs.C[Position]()),
+		)
+		assert.Nil(t, err)
+
+		assert.True(t, w.Alive(parent))
+		assert.True(t, w.Alive(child))
+		assert.Equal(t, Position{X: 3, Y: 4}, *ecs.NewMap[Position](w).Get(child))
+		assert.False(t, w.Unsafe().Has(child, ecs.ComponentID[ChildOf](w)))
+		assert.True(t, w.Unsafe().Has(parent, posId))
+		assert.Equal(t, Position{X: 1000}, *ecs.GetResource[Position](w))
+	}
+
+	// Included types must be registered.
+	w := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w)
+	err = arkserde.Deserialize(jsonData, w,
+		arkserde.Opts.IncludeComponents(ecs.C[Position]()),
+		arkserde.Opts.IncludeResources(ecs.C[Velocity]()),
+	)
+	assert.EqualError(t, err, "Resources: arkserde_test.Velocity: resource type is not registered")
+
+	w = ecs.NewWorld(1024)
+	problems := arkserde.Validate(jsonData, w,
+		arkserde.Opts.IncludeComponents(ecs.C[Velocity]()),
+		arkserde.Opts.IncludeResources(ecs.C[Velocity]()),
+	)
+	assert.Len(t, problems, 2)
+}
+
 func TestDeserializeErrors(t *testing.T) {
 	world := createWorld(false)
 
@@ -229,6 +279,60 @@ func TestDeserializeErrors(t *testing.T) {
 	assert.Contains(t, err.Error(), "unexpected EOF")
 }
 
+func TestDeserializeUnchangedOnError(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		w := createArchetypesWorld()
+		ecs.AddResource(w, &Velocity{X: 1})
+		ecs.AddResource(w, &Position{X: 2})
+
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		// The Velocity resource is decoded after all entities and the Position resource.
+		text := replaceLast(string(jsonData), "\"X\":1", "\"X\":true")
+
+		w2 := createWorld(true)
+		ecs.AddResource(w2, &Velocity{X: 10})
+		ecs.AddResource(w2, &Position{X: 20})
+		err = arkserde.Deserialize([]byte(text), w2, opts...)
+		assert.NotNil(t, err)
+
+		assert.Equal(t, 0, countAll(w2))
+		assert.Equal(t, Velocity{X: 10}, *ecs.GetResource[Velocity](w2))
+		assert.Equal(t, Position{X: 20}, *ecs.GetResource[Position](w2))
+
+		err = arkserde.Deserialize(jsonData, w2, opts...)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, w, w2)
+	}
+
+	// Resources added from the global registry are removed on error.
+	w := ecs.NewWorld(1024)
+	ecs.NewMap1[RegPosition](w).NewEntity(&RegPosition{X: 2})
+	ecs.AddResource(w, &RegConfig{Steps: 10})
+	ecs.AddResource(w, &Velocity{X: 1})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	text := replaceLast(string(jsonData), "\"X\":1", "\"X\":true")
+	w2 := ecs.NewWorld(1024)
+	ecs.AddResource(w2, &Velocity{})
+	err = arkserde.Deserialize([]byte(text), w2)
+	assert.NotNil(t, err)
+	res := ecs.NewResource[RegConfig](w2)
+	assert.False(t, res.Has())
+	assert.Equal(t, 0, countAll(w2))
+}
+
+// replaceLast replaces the last occurrence of old in text.
+func replaceLast(text, old, new string) string {
+	idx := strings.LastIndex(text, old)
+	return text[:idx] + new + text[idx+len(old):]
+}
+
 func createWorld(vel bool) *ecs.World {
 	world := ecs.NewWorld(1024)
 	_ = ecs.ComponentID[Position](world)
@@ -240,6 +344,59 @@ func createWorld(vel bool) *ecs.World {
 	return world
 }
 
+// createArchetypesWorld creates a world like createWorld(true), with entities in multiple archetypes,
+// relations to alive and zero targets, and dead entities.
+func createArchetypesWorld() *ecs.World {
+	w := createWorld(true)
+
+	posMap := ecs.NewMap1[Position](w)
+	posVelMap := ecs.NewMap2[Position, Velocity](w)
+	relMap := ecs.NewMap2[Position, ChildRelation](w)
+
+	parents := []ecs.Entity{}
+	for i := range 3 {
+		parents = append(parents, posMap.NewEntity(&Position{X: float64(i)}))
+	}
+	w.NewEntities(5, nil)
+	cnt := 0
+	posVelMap.NewBatchFn(10, func(_ ecs.Entity, pos *Position, vel *Velocity) {
+		pos.X = float64(cnt)
+		vel.Y = float64(cnt)
+		cnt++
+	})
+	for _, parent := range parents {
+		relMap.NewBatchFn(4, func(_ ecs.Entity, pos *Position, rel *ChildRelation) {
+			pos.Y = float64(cnt)
+			rel.Dummy = cnt
+			cnt++
+		}, ecs.RelIdx(1, parent))
+	}
+	relMap.NewBatchFn(2, nil, ecs.RelIdx(1, ecs.Entity{}))
+
+	// Create some dead entities
+	w.RemoveEntity(parents[2])
+	w.RemoveEntity(posMap.NewEntity(&Position{}))
+
+	return w
+}
+
+// newWorldLike creates an empty world with the component types and resources of the given world,
+// for loading data serialized from it. Resources are added as zero values.
+func newWorldLike(w *ecs.World) *ecs.World {
+	world := ecs.NewWorld(1024)
+	for _, id := range ecs.ComponentIDs(w) {
+		if info, ok := ecs.ComponentInfo(w, id); ok {
+			_ = ecs.TypeID(world, info.Type)
+		}
+	}
+	for _, id := range ecs.ResourceIDs(w) {
+		if tp, ok := ecs.ResourceType(w, id); ok && w.Resources().Get(id) != nil {
+			world.Resources().Add(ecs.ResourceTypeID(world, tp), reflect.New(tp).Interface())
+		}
+	}
+	return world
+}
+
 func TestDeserializeGZip(t *testing.T) {
 	world := ecs.NewWorld(1024)
 
@@ -302,6 +459,224 @@ func TestDeserializeRandSource(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestDeserializeAlias(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	posMap := ecs.NewMap[Position](w)
+	relMap := ecs.NewMap[ChildRelation](w)
+	vel := &Velocity{}
+	ecs.AddResource(w, vel)
+
+	options := []arkserde.Option{
+		arkserde.Opts.Alias("oldpkg.Pos", ecs.C[Position]()),
+		arkserde.Opts.Alias("oldpkg.Rel", ecs.C[ChildRelation]()),
+		arkserde.Opts.Alias("oldpkg.Vel", ecs.C[Velocity]()),
+	}
+
+	err := arkserde.Deserialize([]byte(textAlias), w, options...)
+	assert.Nil(t, err)
+
+	query := ecs.NewUnsafeFilter(w).Query()
+	assert.Equal(t, 2, query.Count())
+	query.Next()
+	parent := query.Entity()
+	query.Next()
+	child := query.Entity()
+	query.Close()
+
+	assert.Equal(t, Position{X: 1, Y: 2}, *posMap.Get(parent))
+	assert.Equal(t, Position{X: 3, Y: 4}, *posMap.Get(child))
+	assert.Equal(t, ChildRelation{Dummy: 5}, *relMap.Get(child))
+	assert.Equal(t, parent, relMap.GetRelation(child))
+	assert.Equal(t, Velocity{X: 1000}, *vel)
+
+	w = ecs.NewWorld(1024)
+	posMap = ecs.NewMap[Position](w)
+	relMap = ecs.NewMap[ChildRelation](w)
+	vel = &Velocity{}
+	ecs.AddResource(w, vel)
+
+	err = arkserde.DeserializeFrom(bytes.NewReader([]byte(textAlias)), w, options...)
+	assert.Nil(t, err)
+	assert.Equal(t, Position{X: 3, Y: 4}, *posMap.Get(child))
+	assert.Equal(t, parent, relMap.GetRelation(child))
+	assert.Equal(t, Velocity{X: 1000}, *vel)
+
+	w = ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w)
+	_ = ecs.ComponentID[ChildRelation](w)
+	err = arkserde.Deserialize([]byte(textAlias), w, arkserde.Opts.Alias("oldpkg.Pos", ecs.C[Position]()))
+	assert.Contains(t, err.Error(), "oldpkg.Rel: component type is not registered")
+
+	w = ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w)
+	_ = ecs.ComponentID[Velocity](w)
+	err = arkserde.Deserialize([]byte(textAlias), w, arkserde.Opts.Alias("arkserde_test.Velocity", ecs.C[Position]()))
+	assert.Contains(t, err.Error(), "component types github.com/mlange-42/ark-serde_test.Velocity and github.com/mlange-42/ark-serde_test.Position have the same name arkserde_test.Velocity")
+
+	w = ecs.NewWorld(1024)
+	ecs.AddResource(w, &Velocity{})
+	ecs.AddResource(w, &Position{})
+	err = arkserde.Deserialize([]byte(textAlias), w, arkserde.Opts.SkipEntities(), arkserde.Opts.Alias("arkserde_test.Velocity", ecs.C[Position]()))
+	assert.Contains(t, err.Error(), "resource types")
+}
+
+func TestDeserializeFrom(t *testing.T) {
+	jsonData, parent, child, err := serialize()
+	assert.Nil(t, err)
+
+	w := ecs.NewWorld(1024)
+	posId := ecs.ComponentID[Position](w)
+	velId := ecs.ComponentID[Velocity](w)
+	childId := ecs.ComponentID[ChildOf](w)
+
+	ecs.AddResource(w, &Position{})
+	ecs.AddResource(w, &Velocity{})
+
+	err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w)
+	assert.Nil(t, err)
+
+	query := ecs.NewUnsafeFilter(w).Query()
+	assert.Equal(t, query.Count(), 3)
+
+	query.Next()
+	assert.False(t, query.Has(posId))
+
+	query.Next()
+	assert.True(t, query.Has(posId))
+	assert.False(t, query.Has(velId))
+	assert.Equal(t, *(*Position)(query.Get(posId)), Position{X: 1, Y: 2})
+
+	query.Next()
+	assert.Equal(t, *(*Position)(query.Get(posId)), Position{X: 3, Y: 4})
+	assert.Equal(t, *(*Velocity)(query.Get(velId)), Velocity{X: 5, Y: 6})
+	assert.Equal(t, *(*ChildOf)(query.Get(childId)), ChildOf{Entity: parent})
+
+	res := ecs.GetResource[Velocity](w)
+	assert.Equal(t, *res, Velocity{X: 1000})
+
+	assert.True(t, w.Alive(parent))
+	assert.True(t, w.Alive(child))
+}
+
+func TestDeserializeFromGZip(t *testing.T) {
+	world := ecs.NewWorld(1024)
+
+	builder := ecs.NewMap2[Position, Velocity](world)
+	cnt := 0
+	builder.NewBatchFn(100, func(entity ecs.Entity, pos *Position, _ *Velocity) {
+		pos.X = float64(cnt)
+		cnt++
+	})
+
+	buffer := bytes.Buffer{}
+	err := arkserde.SerializeTo(&buffer, world, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	world1 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](world1)
+	_ = ecs.ComponentID[Velocity](world1)
+
+	err = arkserde.DeserializeFrom(&buffer, world1, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	filter := ecs.NewFilter2[Position, Velocity](world1)
+	query := filter.Query()
+	assert.Equal(t, 100, query.Count())
+
+	cnt = 0
+	for query.Next() {
+		pos, _ := query.Get()
+		assert.EqualValues(t, cnt, pos.X)
+		cnt++
+	}
+	assert.Equal(t, 100, cnt)
+}
+
+func TestDeserializeFromSkipEntities(t *testing.T) {
+	jsonData, _, _, err := serialize()
+	assert.Nil(t, err)
+
+	w := ecs.NewWorld(1024)
+	ecs.AddResource(w, &Position{})
+	ecs.AddResource(w, &Velocity{})
+
+	err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w, arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+
+	query := ecs.NewUnsafeFilter(w).Query()
+	assert.Equal(t, query.Count(), 0)
+	query.Close()
+
+	res := ecs.GetResource[Velocity](w)
+	assert.Equal(t, *res, Velocity{X: 1000})
+
+	jsonData, _, _, err = serialize(arkserde.Opts.SkipEntities())
+	assert.Nil(t, err)
+
+	w = ecs.NewWorld(1024)
+	ecs.AddResource(w, &Position{})
+	ecs.AddResource(w, &Velocity{})
+
+	err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w)
+	assert.Nil(t, err)
+}
+
+func TestDeserializeFromErrors(t *testing.T) {
+	deserialize := func(text string, vel bool, options ...arkserde.Option) error {
+		world := createWorld(vel)
+		if vel {
+			ecs.AddResource(world, &Velocity{})
+		}
+		return arkserde.DeserializeFrom(bytes.NewReader([]byte(text)), world, options...)
+	}
+
+	err := deserialize(textOk, true)
+	assert.Nil(t, err)
+
+	err = deserialize("{xxx}", true)
+	assert.Contains(t, err.Error(), "invalid char")
+
+	err = deserialize("[]", true)
+	assert.Contains(t, err.Error(), "expected {, got [")
+
+	err = deserialize(textOk, false)
+	assert.Contains(t, err.Error(), "component type is not registered")
+
+	err = deserialize(textErrEntities, true)
+	assert.Contains(t, err.Error(), "found components for 1 entities, but world has 2 alive entities")
+
+	for _, text := range []string{textErrNoComponents, textErrNoComponentsTypes} {
+		err = deserialize(text, true)
+		assert.EqualError(t, err, "Components: found components for 0 entities, but world has 1 alive entities")
+
+		w := createWorld(true)
+		ecs.AddResource(w, &Velocity{})
+		assert.Equal(t, err, arkserde.Deserialize([]byte(text), w))
+		assert.Equal(t, []string{err.Error()}, problemStrings(arkserde.Validate([]byte(text), createWorld(true))))
+	}
+
+	err = deserialize(textErrEntitiesTooMany, true)
+	assert.Contains(t, err.Error(), "found components for more than 1 entities, but world has 1 alive entities")
+
+	err = deserialize(textErrComponent, true)
+	assert.Contains(t, err.Error(), "expected { character for map value")
+
+	err = deserialize(textErrResource, true)
+	assert.Contains(t, err.Error(), "invalid character '[' looking for beginning of value")
+
+	err = deserialize(textErrOrder, true)
+	assert.Contains(t, err.Error(), "section Types must precede section Components")
+
+	err = deserialize(textOrderNoTypes, true)
+	assert.Nil(t, err)
+
+	err = deserialize(textErrOrderWorld, true)
+	assert.Contains(t, err.Error(), "section World must precede sections Types and Components")
+
+	err = deserialize("abc", true, arkserde.Opts.Compress())
+	assert.Contains(t, err.Error(), "unexpected EOF")
+}
+
 const textOk = `{
 	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
 	"Types" : [
@@ -323,6 +698,26 @@ const textOk = `{
 		"arkserde_test.Velocity" : {"X":1000,"Y":0}
 	}}`
 
+const textAlias = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
+	"Types" : [
+	  "oldpkg.Pos",
+	  "oldpkg.Rel"
+	],
+	"Components" : [
+	  {
+		"oldpkg.Pos" : {"X":1,"Y":2}
+	  },
+	  {
+		"oldpkg.Pos" : {"X":3,"Y":4},
+		"oldpkg.Rel.ark.relation.Target" : [2,0],
+		"oldpkg.Rel" : {"Dummy":5}
+	  }
+	],
+	"Resources" : {
+		"oldpkg.Vel" : {"X":1000,"Y":0}
+	}}`
+
 const textErrEntities = `{
 	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
 	"Types" : [
@@ -341,6 +736,64 @@ const textErrEntities = `{
 		"arkserde_test.Velocity" : {"X":1000,"Y":0}
 	}}`
 
+const textErrEntitiesTooMany = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Types" : [
+		"arkserde_test.Position"
+	],
+	"Components" : [
+		{
+		"arkserde_test.Position" : {"X":1,"Y":2}
+		},
+		{
+		"arkserde_test.Position" : {"X":3,"Y":4}
+		}
+	],
+	"Resources" : {}}`
+
+const textErrOrder = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Components" : [
+		{
+		"arkserde_test.Position" : {"X":1,"Y":2}
+		}
+	],
+	"Types" : [
+		"arkserde_test.Position"
+	],
+	"Resources" : {}}`
+
+const textOrderNoTypes = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Components" : [
+		{
+		"arkserde_test.Position" : {"X":1,"Y":2}
+		}
+	],
+	"Resources" : {}}`
+
+const textErrNoComponents = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Resources" : {}}`
+
+const textErrNoComponentsTypes = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Types" : ["arkserde_test.Position"],
+	"Resources" : {}}`
+
+const textErrOrderWorld = `{
+	"Types" : [
+		"arkserde_test.Position"
+	],
+	"Components" : [],
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+	"Components" : [
+		{
+		"arkserde_test.Position" : {"X":1,"Y":2}
+		}
+	],
+	"Resources" : {}}`
+
 const textErrTypes = `{
 	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
 	"Types" : {"a": "b"},
@@ -425,6 +878,147 @@ const textErrResource = `{
 		"arkserde_test.Velocity" : []
 	}}`
 
+type MemberOf struct {
+	ecs.RelationMarker
+	Rank int
+}
+
+type LinkedTo struct {
+	ecs.RelationMarker
+}
+
+func TestDeserializeMultipleRelations(t *testing.T) {
+	w := createArchetypesWorld()
+	posMap := ecs.NewMap1[Position](w)
+	relMap := ecs.NewMap4[Position, ChildRelation, MemberOf, LinkedTo](w)
+
+	parents := []ecs.Entity{}
+	for i := range 4 {
+		parents = append(parents, posMap.NewEntity(&Position{X: float64(i)}))
+	}
+	for i := range 3 {
+		relMap.NewEntity(&Position{Y: float64(i)}, &ChildRelation{Dummy: i}, &MemberOf{Rank: i}, &LinkedTo{},
+			ecs.RelIdx(1, parents[i]), ecs.RelIdx(2, parents[(i+1)%3]), ecs.RelIdx(3, parents[3]))
+	}
+	relMap.NewEntity(&Position{}, &ChildRelation{}, &MemberOf{Rank: 5}, &LinkedTo{},
+		ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parents[0]), ecs.RelIdx(3, ecs.Entity{}))
+	ecs.NewMap2[Position, MemberOf](w).NewEntity(&Position{}, &MemberOf{Rank: 10}, ecs.RelIdx(1, parents[1]))
+
+	// Targets of removed entities are reset to zero.
+	w.RemoveEntity(parents[3])
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := newWorldLike(w)
+		err = arkserde.Deserialize(jsonData, w2)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, w, w2)
+
+		w2 = newWorldLike(w)
+		err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w2)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, w, w2)
+
+		// Skipping one of the relations keeps the targets of the others.
+		expected := w2
+		memberID := ecs.ComponentID[MemberOf](expected)
+		members := []ecs.Entity{}
+		query := ecs.NewFilter1[MemberOf](expected).Query()
+		for query.Next() {
+			members = append(members, query.Entity())
+		}
+		for _, e := range members {
+			expected.Unsafe().Remove(e, memberID)
+		}
+
+		w2 = newWorldLike(w)
+		err = arkserde.Deserialize(jsonData, w2, arkserde.Opts.SkipComponents(ecs.C[MemberOf]()))
+		assert.Nil(t, err)
+		assertEqualWorlds(t, expected, w2)
+
+		jsonData, err = arkserde.Serialize(w, append(opts, arkserde.Opts.SkipComponents(ecs.C[MemberOf]()))...)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(jsonData), "MemberOf")
+
+		w2 = newWorldLike(w)
+		err = arkserde.Deserialize(jsonData, w2)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, expected, w2)
+	}
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+}
+
+func TestDeserializeRelationErrors(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{})
+	ecs.NewMap2[Position, ChildRelation](w).NewEntity(&Position{}, &ChildRelation{}, ecs.RelIdx(1, parent))
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	text := string(jsonData)
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	columnar := string(jsonData)
+
+	target := `"arkserde_test.ChildRelation.ark.relation.Target" : [2,0]`
+	columnarTarget := `"arkserde_test.ChildRelation" : [2,0]`
+
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{strings.Replace(text, target+",", "", 1), "arkserde_test.ChildRelation: missing relation target"},
+		{strings.Replace(text, target, `"arkserde_test.ChildRelation.ark.relation.Target" : [2,1]`, 1), "arkserde_test.ChildRelation: relation target {2 1} is not alive"},
+		{strings.Replace(text, target, `"arkserde_test.ChildRelation.ark.relation.Target" : [5,0]`, 1), "arkserde_test.ChildRelation: relation target {5 0} is not alive"},
+		{strings.Replace(text, target, `"arkserde_test.Position.ark.relation.Target" : [2,0]`, 1), "arkserde_test.Position: found relation target, but type is not a relation"},
+		{strings.Replace(text, target, `"arkserde_test.Unknown.ark.relation.Target" : [2,0]`, 1), "arkserde_test.Unknown: component type is not registered"},
+		{strings.Replace(columnar, columnarTarget, "", 1), "arkserde_test.ChildRelation: missing relation target"},
+		{strings.Replace(columnar, columnarTarget, `"arkserde_test.ChildRelation" : [2,1]`, 1), "arkserde_test.ChildRelation: relation target {2 1} is not alive"},
+		{strings.Replace(columnar, columnarTarget, `"arkserde_test.Position" : [2,0]`, 1), "arkserde_test.Position: found relation target, but type is not a relation"},
+	}
+
+	for _, tt := range tests {
+		w2 := ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Position](w2)
+		_ = ecs.ComponentID[ChildRelation](w2)
+
+		err := arkserde.Deserialize([]byte(tt.text), w2)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+		assert.Equal(t, 0, countAll(w2))
+
+		w2 = ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Position](w2)
+		_ = ecs.ComponentID[ChildRelation](w2)
+
+		err = arkserde.DeserializeFrom(strings.NewReader(tt.text), w2)
+		if assert.NotNil(t, err) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+
+		w2 = ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Position](w2)
+		_ = ecs.ComponentID[ChildRelation](w2)
+
+		problems := problemStrings(arkserde.Validate([]byte(tt.text), w2))
+		assert.Contains(t, strings.Join(problems, "\n"), tt.err)
+	}
+}
+
 func benchmarkDeserializeJSON(n int, b *testing.B) {
 	w := ecs.NewWorld(1024)
 
@@ -0,0 +1,83 @@
+package arkserde
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// DecodeError is an error in serialized data, with the location where it was found.
+//
+// Errors caused by the data when deserializing can be checked for it with [errors.As]:
+//
+//	var decErr *arkserde.DecodeError
+//	if errors.As(err, &decErr) {
+//		fmt.Println(decErr.Section, decErr.Entity, decErr.Type)
+//	}
+type DecodeError struct {
+	Section string     // Section of the data, like "World", "Types", "Components", "Archetypes", "Resources", or "Baseline" and "Roots" for deltas and prefabs. Empty for the document as a whole.
+	Index   int        // Index of the entity in the list of alive entities, or of the archetype. -1 if not applicable.
+	Entity  ecs.Entity // The serialized entity at Index, for sections "World" and "Components". Zero if not applicable.
+	Type    string     // Name of the component or resource type. Empty if not applicable.
+	Err     error      // The underlying error.
+}
+
+// Problem is a problem found by [Validate].
+// It carries the same location as the errors returned by [Deserialize] for the same data.
+type Problem = DecodeError
+
+// Error returns a description of the error, including its location.
+func (e DecodeError) Error() string {
+	b := strings.Builder{}
+	if e.Section != "" {
+		b.WriteString(e.Section)
+		b.WriteString(": ")
+	}
+	if e.Index >= 0 {
+		if e.Section == "Archetypes" {
+			fmt.Fprintf(&b, "archetype %d", e.Index)
+		} else {
+			fmt.Fprintf(&b, "entity %d", e.Index)
+		}
+		if !e.Entity.IsZero() {
+			fmt.Fprintf(&b, " %v", e.Entity)
+		}
+		b.WriteString(": ")
+	}
+	if e.Type != "" {
+		b.WriteString(e.Type)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// newDecodeError creates a [DecodeError] without an entity.
+func newDecodeError(section string, index int, tp string, err error) *DecodeError {
+	return &DecodeError{Section: section, Index: index, Type: tp, Err: err}
+}
+
+// wrapDecodeError wraps an error into a [DecodeError] for a section, unless it already is one.
+func wrapDecodeError(section string, err error) error {
+	var decErr *DecodeError
+	if errors.As(err, &decErr) {
+		return err
+	}
+	return newDecodeError(section, -1, "", err)
+}
+
+// serializedEntity returns the serialized entity for an index in the list of alive entities.
+// Returns the zero entity if the index is out of range.
+func serializedEntity(dump *ecs.EntityDump, index int) ecs.Entity {
+	if index < 0 || index >= len(dump.Alive) || int(dump.Alive[index]) >= len(dump.Entities) {
+		return ecs.Entity{}
+	}
+	return dump.Entities[dump.Alive[index]]
+}
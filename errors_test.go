@@ -0,0 +1,248 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeError(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+	posMap.NewEntity(&Position{X: 1})
+	posMap.NewEntity(&Position{X: 2})
+	ecs.AddResource(w, &Velocity{X: 3})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	text := strings.Replace(string(jsonData), `{"X":2,"Y":0}`, `{"X":true,"Y":0}`, 1)
+
+	for _, load := range []func(string, *ecs.World) error{
+		func(text string, w *ecs.World) error { return arkserde.Deserialize([]byte(text), w) },
+		func(text string, w *ecs.World) error { return arkserde.DeserializeFrom(strings.NewReader(text), w) },
+		func(text string, w *ecs.World) error {
+			_, err := arkserde.Merge([]byte(text), w)
+			return err
+		},
+	} {
+		err = load(text, newWorldLike(w))
+
+		var decErr *arkserde.DecodeError
+		if !assert.True(t, errors.As(err, &decErr)) {
+			continue
+		}
+		assert.Equal(t, "Components", decErr.Section)
+		assert.Equal(t, 1, decErr.Index)
+		assert.Equal(t, "{3 0}", fmt.Sprint(decErr.Entity))
+		assert.Equal(t, "arkserde_test.Position", decErr.Type)
+		assert.NotNil(t, decErr.Err)
+		assert.True(t, strings.HasPrefix(err.Error(), "Components: entity 1 {3 0}: arkserde_test.Position: "), err.Error())
+	}
+}
+
+func TestDecodeErrorSections(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+	posMap.NewEntity(&Position{X: 1})
+	posMap.NewEntity(&Position{X: 2})
+	ecs.AddResource(w, &Velocity{X: 3})
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	text := string(jsonData)
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	columnar := string(jsonData)
+
+	tests := []struct {
+		text    string
+		section string
+		index   int
+		tp      string
+		err     string
+	}{
+		{"{xxx}", "", -1, "", ""},
+		{strings.Replace(text, `"Format":1`, `"Format":1000`, 1), "Version", -1, "", "Version: unsupported format version 1000"},
+		{strings.Replace(text, `"arkserde_test.Position"`, `"arkserde_test.Unknown"`, 1), "Types", -1, "arkserde_test.Unknown", "Types: arkserde_test.Unknown: component type is not registered"},
+		{strings.Replace(columnar, `{"X":2,"Y":0}`, `{"X":true,"Y":0}`, 1), "Archetypes", 0, "arkserde_test.Position", "Archetypes: archetype 0: arkserde_test.Position: "},
+		{strings.Replace(text, `{"X":3,"Y":0}`, `{"X":true,"Y":0}`, 1), "Resources", -1, "arkserde_test.Velocity", "Resources: arkserde_test.Velocity: "},
+	}
+
+	for _, tt := range tests {
+		for _, err := range []error{
+			arkserde.Deserialize([]byte(tt.text), newWorldLike(w)),
+			arkserde.DeserializeFrom(bytes.NewReader([]byte(tt.text)), newWorldLike(w)),
+		} {
+			var decErr *arkserde.DecodeError
+			if !assert.True(t, errors.As(err, &decErr), "expected a DecodeError, got %v", err) {
+				continue
+			}
+			assert.Equal(t, tt.section, decErr.Section)
+			assert.Equal(t, tt.index, decErr.Index)
+			assert.Equal(t, tt.tp, decErr.Type)
+			assert.True(t, strings.HasPrefix(err.Error(), tt.err), err.Error())
+			assert.Equal(t, decErr.Err, errors.Unwrap(decErr))
+		}
+	}
+}
+
+func TestDecodeErrorValidate(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+	posMap.NewEntity(&Position{X: 1})
+	posMap.NewEntity(&Position{X: 2})
+	ecs.AddResource(w, &Velocity{X: 3})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	text := strings.Replace(string(jsonData), `{"X":2,"Y":0}`, `{"X":true,"Y":0}`, 1)
+
+	problems := arkserde.Validate([]byte(text), newWorldLike(w))
+	if !assert.Len(t, problems, 1) {
+		return
+	}
+	err = arkserde.Deserialize([]byte(text), newWorldLike(w))
+	var decErr *arkserde.DecodeError
+	assert.True(t, errors.As(err, &decErr))
+
+	assert.Equal(t, *decErr, problems[0])
+	assert.Equal(t, "{3 0}", fmt.Sprint(problems[0].Entity))
+}
+
+func TestDecodeErrorEntityPool(t *testing.T) {
+	tests := []struct {
+		world string
+		alive int
+		err   string
+	}{
+		{`{"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[7],"Next":0,"Available":0}`, 1, "World: entity 0: alive entity index 7 out of range for 3 entities"},
+		{`{"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,2],"Next":0,"Available":0}`, 2, "World: entity 1 {2 0}: alive entity index 2 appears more than once"},
+		{`{"Entities":[[0,4294967295],[1,4294967295],[3,0]],"Alive":[2],"Next":0,"Available":0}`, 1, "World: entity 0 {3 0}: entity {3 0} found at index 2"},
+		{`{"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":1}`, 1, "World: found 1 alive and 1 available entities, but only 3 entities"},
+	}
+
+	for _, tt := range tests {
+		components := strings.Repeat(`{"arkserde_test.Position" : {"X":1,"Y":2}},`, tt.alive)
+		text := fmt.Sprintf(`{
+"World" : %s,
+"Types" : ["arkserde_test.Position"],
+"Components" : [%s],
+"Resources" : {}
+}`, tt.world, strings.TrimSuffix(components, ","))
+
+		for _, load := range []func(string, *ecs.World) error{
+			func(text string, w *ecs.World) error { return arkserde.Deserialize([]byte(text), w) },
+			func(text string, w *ecs.World) error { return arkserde.DeserializeFrom(strings.NewReader(text), w) },
+			func(text string, w *ecs.World) error {
+				_, err := arkserde.Merge([]byte(text), w)
+				return err
+			},
+		} {
+			w := createWorld(false)
+			err := load(text, w)
+
+			var decErr *arkserde.DecodeError
+			if !assert.True(t, errors.As(err, &decErr), "expected a DecodeError, got %v", err) {
+				continue
+			}
+			assert.Equal(t, "World", decErr.Section)
+			assert.EqualError(t, err, tt.err)
+			assert.Equal(t, 0, countAll(w))
+		}
+
+		problems := arkserde.Validate([]byte(text), createWorld(false))
+		assert.Equal(t, []string{tt.err}, problemStrings(problems))
+	}
+}
+
+func TestDecodeErrorWorldNotEmpty(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+	posMap.NewEntity(&Position{X: 1})
+	posMap.NewEntity(&Position{X: 2})
+	ecs.AddResource(w, &Velocity{X: 3})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	delta, err := arkserde.SerializeDelta(jsonData, w)
+	assert.Nil(t, err)
+	binary, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	for _, load := range []func(w *ecs.World) error{
+		func(w *ecs.World) error { return arkserde.Deserialize(jsonData, w) },
+		func(w *ecs.World) error { return arkserde.DeserializeFrom(bytes.NewReader(jsonData), w) },
+		func(w *ecs.World) error { return arkserde.ApplyDelta(jsonData, delta, w) },
+		func(w *ecs.World) error { return arkserde.DeserializeBinary(binary, w) },
+	} {
+		w2 := newWorldLike(w)
+		w2.NewEntity()
+		err := load(w2)
+
+		var decErr *arkserde.DecodeError
+		if !assert.True(t, errors.As(err, &decErr), "expected a DecodeError, got %v", err) {
+			continue
+		}
+		assert.EqualError(t, err, "World: world must not contain any alive or dead entities")
+		assert.Equal(t, 1, countAll(w2))
+		assert.Equal(t, Velocity{}, *ecs.GetResource[Velocity](w2))
+	}
+}
+
+func TestDecodeErrorFormats(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+	posMap.NewEntity(&Position{X: 1})
+	posMap.NewEntity(&Position{X: 2})
+	ecs.AddResource(w, &Velocity{X: 3})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	delta, err := arkserde.SerializeDelta(jsonData, w)
+	assert.Nil(t, err)
+	binary, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	otherDelta := strings.Replace(string(delta), `"Baseline" : "`, `"Baseline" : "x`, 1)
+
+	tests := []struct {
+		load    func(w *ecs.World) error
+		section string
+		tp      string
+		err     string
+	}{
+		{func(w *ecs.World) error { return arkserde.DeserializeBinary([]byte("{}"), w) }, "", "", "data is not in the binary format"},
+		{func(w *ecs.World) error { return arkserde.DeserializeBinary(binary[:len(binary)-2], w) }, "Resources", "", "Resources: unexpected end of binary data"},
+		{func(w *ecs.World) error {
+			// Position is stored with a different layout.
+			w = ecs.NewWorld(1024)
+			_ = ecs.ComponentID[Position3D](w)
+			ecs.AddResource(w, &Velocity{})
+			return arkserde.DeserializeBinary(binary, w, arkserde.Opts.Alias("arkserde_test.Position", ecs.C[Position3D]()))
+		}, "Types", "arkserde_test.Position", "Types: arkserde_test.Position: memory layout does not match the serialized data"},
+		{func(w *ecs.World) error { return arkserde.ApplyDelta(jsonData, []byte(otherDelta), w) }, "Baseline", "", "Baseline: delta was not created for this baseline"},
+		{func(w *ecs.World) error { return arkserde.ApplyDelta(jsonData, delta[:len(delta)/2], w) }, "", "", ""},
+		{func(w *ecs.World) error { return arkserde.ApplyDelta([]byte("{xxx}"), delta, w) }, "Baseline", "", "Baseline: "},
+		{func(w *ecs.World) error {
+			_, err := arkserde.Instantiate([]byte(textPrefabErrRoot), w)
+			return err
+		}, "Roots", "", "Roots: root index 1 out of range for 1 entities"},
+	}
+
+	for _, tt := range tests {
+		w2 := newWorldLike(w)
+		err := tt.load(w2)
+
+		var decErr *arkserde.DecodeError
+		if !assert.True(t, errors.As(err, &decErr), "expected a DecodeError, got %v", err) {
+			continue
+		}
+		assert.Equal(t, tt.section, decErr.Section)
+		assert.Equal(t, tt.tp, decErr.Type)
+		assert.True(t, strings.HasPrefix(err.Error(), tt.err), err.Error())
+		assert.Equal(t, 0, countAll(w2))
+	}
+}
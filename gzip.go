@@ -6,24 +6,19 @@ import (
 	"io"
 )
 
-func compressGZip(data []byte, level int) ([]byte, error) {
-	var buffer bytes.Buffer
-	writer, err := gzip.NewWriterLevel(&buffer, level)
+// writeGZip calls fn with a writer that compresses
+// everything written to it, and passes the result on to w.
+func writeGZip(w io.Writer, level int, fn func(w io.Writer) error) error {
+	writer, err := gzip.NewWriterLevel(w, level)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	_, err = writer.Write(data)
-	if err != nil {
-		return nil, err
+	if err := fn(writer); err != nil {
+		return err
 	}
 
-	err = writer.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	return buffer.Bytes(), nil
+	return writer.Close()
 }
 
 func uncompressGZip(data []byte) ([]byte, error) {
@@ -45,3 +40,23 @@ func uncompressGZip(data []byte) ([]byte, error) {
 
 	return buffer.Bytes(), nil
 }
+
+// readGZip calls fn with a reader that decompresses
+// everything read from r.
+func readGZip(r io.Reader, fn func(r io.Reader) error) error {
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(reader); err != nil {
+		return err
+	}
+
+	return reader.Close()
+}
+
+// isGZip checks whether data starts with the gzip magic number.
+func isGZip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
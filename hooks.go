@@ -0,0 +1,112 @@
+package arkserde
+
+import (
+	"reflect"
+	"unsafe"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// BeforeSerializer can be implemented by components and resources that need to prepare their data
+// before they are serialized, e.g. to clear caches or to sync derived data.
+//
+// BeforeSerialize is called on a pointer to the world's value, right before it is encoded.
+// It is not called for skipped components and resources.
+type BeforeSerializer interface {
+	BeforeSerialize()
+}
+
+// AfterDeserializer can be implemented by components that need to rebuild caches or derived data
+// after they were deserialized.
+//
+// AfterDeserialize is called on a pointer to the world's component, with the entity it belongs to.
+// It is called after all entities and resources were loaded, so that it can access them,
+// but before the callbacks of [Options.OnLoaded].
+// It should not add or remove components of the entity.
+//
+// An error returned by the hook is returned as a [*DecodeError].
+// In this case, the data was already added to the world.
+type AfterDeserializer interface {
+	AfterDeserialize(world *ecs.World, entity ecs.Entity) error
+}
+
+// ResourceAfterDeserializer is the variant of [AfterDeserializer] for resources.
+//
+// AfterDeserialize is called on the world's resource,
+// after all resources and entities were loaded, and before the hooks of components.
+type ResourceAfterDeserializer interface {
+	AfterDeserialize(world *ecs.World) error
+}
+
+var afterDeserializerType = reflect.TypeFor[AfterDeserializer]()
+var beforeSerializerType = reflect.TypeFor[BeforeSerializer]()
+
+// beforeSerialize calls the [BeforeSerializer] hook of the value of the given type at ptr, if it has one.
+func beforeSerialize(tp reflect.Type, ptr unsafe.Pointer) {
+	if !reflect.PointerTo(tp).Implements(beforeSerializerType) {
+		return
+	}
+	reflect.NewAt(tp, ptr).Interface().(BeforeSerializer).BeforeSerialize()
+}
+
+// afterLoad runs the hooks of the loaded resources and components,
+// followed by the callbacks of [Options.OnLoaded].
+// The loader is nil if entities were skipped.
+// The resources are pointers to the loaded resources of the world.
+func afterLoad(world *ecs.World, loader *entityLoader, resources []interface{}, opts *serdeOptions) error {
+	for _, res := range resources {
+		hook, ok := res.(ResourceAfterDeserializer)
+		if !ok {
+			continue
+		}
+		if err := hook.AfterDeserialize(world); err != nil {
+			return newDecodeError("Resources", -1, typeName(reflect.TypeOf(res).Elem(), opts), err)
+		}
+	}
+
+	if loader != nil {
+		if err := loader.afterDeserialize(); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range opts.onLoaded {
+		if err := fn(world); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// afterDeserialize calls the [AfterDeserializer] hooks of the components of all loaded entities.
+func (l *entityLoader) afterDeserialize() error {
+	hooks := bitMask{}
+	hasHooks := false
+	for _, info := range l.infos {
+		if info.Type != nil && reflect.PointerTo(info.Type).Implements(afterDeserializerType) {
+			hooks.Set(info.ID, true)
+			hasHooks = true
+		}
+	}
+	if !hasHooks {
+		return nil
+	}
+
+	u := l.world.Unsafe()
+	for i := range l.entities.Alive {
+		entity := l.entity(i)
+		ids := u.IDs(entity)
+		for j := range ids.Len() {
+			id := ids.Get(j)
+			if !hooks.Get(id) {
+				continue
+			}
+			info := l.infos[id.Index()]
+			hook := reflect.NewAt(info.Type, u.Get(entity, id)).Interface().(AfterDeserializer)
+			if err := hook.AfterDeserialize(l.world, entity); err != nil {
+				return l.entityError(i, typeName(info.Type, l.opts), err)
+			}
+		}
+	}
+	return nil
+}
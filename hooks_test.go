@@ -0,0 +1,213 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+// Path is a component with derived data that is rebuilt after loading.
+type Path struct {
+	Points []float64
+	Length float64    `json:"-"`
+	Owner  ecs.Entity `json:"-"`
+}
+
+func (p *Path) AfterDeserialize(world *ecs.World, entity ecs.Entity) error {
+	if len(p.Points) == 0 {
+		return fmt.Errorf("path without points")
+	}
+	p.Length = 0
+	for _, pt := range p.Points {
+		p.Length += pt
+	}
+	p.Owner = entity
+	return nil
+}
+
+// Counter is a plain-data component that folds pending counts before saving.
+type Counter struct {
+	Value   int32
+	Pending int32
+}
+
+func (c *Counter) BeforeSerialize() {
+	c.Value += c.Pending
+	c.Pending = 0
+}
+
+// Journal is a resource that flushes pending entries before saving.
+type Journal struct {
+	Entries []string
+	pending []string
+}
+
+func (j *Journal) BeforeSerialize() {
+	j.Entries = append(j.Entries, j.pending...)
+	j.pending = nil
+}
+
+// Catalog is a resource with an index that is rebuilt after loading.
+type Catalog struct {
+	Names []string
+	index map[string]int
+}
+
+func (c *Catalog) AfterDeserialize(world *ecs.World) error {
+	c.index = map[string]int{}
+	for i, name := range c.Names {
+		c.index[name] = i
+	}
+	return nil
+}
+
+func TestBeforeSerialize(t *testing.T) {
+	for _, save := range []func(w *ecs.World) ([]byte, error){
+		func(w *ecs.World) ([]byte, error) { return arkserde.Serialize(w) },
+		func(w *ecs.World) ([]byte, error) { return arkserde.Serialize(w, arkserde.Opts.Columnar()) },
+		func(w *ecs.World) ([]byte, error) { return arkserde.SerializeBinary(w) },
+	} {
+		w := createWorld(false)
+		pathMap := ecs.NewMap2[Path, Counter](w)
+		pathMap.NewEntity(&Path{Points: []float64{1, 2}}, &Counter{Value: 1, Pending: 2})
+		pathMap.NewEntity(&Path{Points: []float64{3, 4, 5}}, &Counter{Value: 3, Pending: 4})
+		ecs.AddResource(w, &Journal{pending: []string{"a", "b"}})
+		ecs.AddResource(w, &Catalog{Names: []string{"x", "y"}})
+		data, err := save(w)
+		assert.Nil(t, err)
+
+		// Hooks are called on the world's values.
+		assert.Equal(t, []string{"a", "b"}, ecs.GetResource[Journal](w).Entries)
+		assert.Equal(t, []Counter{{Value: 3}, {Value: 7}}, collectCounters(w))
+
+		w2 := newWorldLike(w)
+		if bytes.HasPrefix(data, []byte("{")) {
+			err = arkserde.Deserialize(data, w2)
+		} else {
+			err = arkserde.DeserializeBinary(data, w2)
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"a", "b"}, ecs.GetResource[Journal](w2).Entries)
+		assert.Equal(t, []Counter{{Value: 3}, {Value: 7}}, collectCounters(w2))
+	}
+}
+
+func collectCounters(w *ecs.World) []Counter {
+	counters := []Counter{}
+	query := ecs.NewFilter1[Counter](w).Query()
+	for query.Next() {
+		counters = append(counters, *query.Get())
+	}
+	return counters
+}
+
+func TestAfterDeserialize(t *testing.T) {
+	w := createWorld(false)
+	pathMap := ecs.NewMap2[Path, Counter](w)
+	pathMap.NewEntity(&Path{Points: []float64{1, 2}}, &Counter{Value: 1, Pending: 2})
+	pathMap.NewEntity(&Path{Points: []float64{3, 4, 5}}, &Counter{Value: 3, Pending: 4})
+	ecs.AddResource(w, &Journal{pending: []string{"a", "b"}})
+	ecs.AddResource(w, &Catalog{Names: []string{"x", "y"}})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	columnar, err := arkserde.Serialize(w, arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+	binary, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	for _, load := range []func(w *ecs.World, opts ...arkserde.Option) error{
+		func(w *ecs.World, opts ...arkserde.Option) error {
+			return arkserde.Deserialize(jsonData, w, opts...)
+		},
+		func(w *ecs.World, opts ...arkserde.Option) error {
+			return arkserde.Deserialize(columnar, w, opts...)
+		},
+		func(w *ecs.World, opts ...arkserde.Option) error {
+			return arkserde.DeserializeFrom(bytes.NewReader(jsonData), w, opts...)
+		},
+		func(w *ecs.World, opts ...arkserde.Option) error {
+			return arkserde.DeserializeBinary(binary, w, opts...)
+		},
+		func(w *ecs.World, opts ...arkserde.Option) error {
+			_, err := arkserde.Merge(jsonData, w, opts...)
+			return err
+		},
+	} {
+		w2 := newWorldLike(w)
+		calls := []string{}
+		err := load(w2,
+			arkserde.Opts.OnLoaded(func(world *ecs.World) error {
+				// Hooks were already called.
+				query := ecs.NewFilter1[Path](world).Query()
+				for query.Next() {
+					path := query.Get()
+					calls = append(calls, fmt.Sprintf("%v %v", path.Length, path.Owner == query.Entity()))
+				}
+				return nil
+			}),
+			arkserde.Opts.OnLoaded(func(world *ecs.World) error {
+				calls = append(calls, fmt.Sprintf("%v", ecs.GetResource[Catalog](world).index))
+				return nil
+			}),
+		)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"3 true", "12 true", "map[x:0 y:1]"}, calls)
+	}
+}
+
+func TestAfterDeserializeSkip(t *testing.T) {
+	w := createWorld(false)
+	pathMap := ecs.NewMap2[Path, Counter](w)
+	pathMap.NewEntity(&Path{Points: []float64{1, 2}}, &Counter{Value: 1, Pending: 2})
+	pathMap.NewEntity(&Path{Points: []float64{3, 4, 5}}, &Counter{Value: 3, Pending: 4})
+	ecs.AddResource(w, &Journal{pending: []string{"a", "b"}})
+	ecs.AddResource(w, &Catalog{Names: []string{"x", "y"}})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w = newWorldLike(w)
+	err = arkserde.Deserialize(jsonData, w, arkserde.Opts.SkipEntities(), arkserde.Opts.SkipResources(ecs.C[Catalog]()))
+	assert.Nil(t, err)
+	assert.Nil(t, ecs.GetResource[Catalog](w).index)
+}
+
+func TestAfterDeserializeErrors(t *testing.T) {
+	w := createWorld(false)
+	pathMap := ecs.NewMap2[Path, Counter](w)
+	pathMap.NewEntity(&Path{Points: []float64{1, 2}}, &Counter{Value: 1, Pending: 2})
+	pathMap.NewEntity(&Path{Points: []float64{3, 4, 5}}, &Counter{Value: 3, Pending: 4})
+	ecs.AddResource(w, &Journal{pending: []string{"a", "b"}})
+	ecs.AddResource(w, &Catalog{Names: []string{"x", "y"}})
+	hooksData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w = newWorldLike(w)
+	pathMap1 := ecs.NewMap1[Path](w)
+	pathMap1.NewEntity(&Path{Points: []float64{1}})
+	pathMap1.NewEntity(&Path{})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	err = arkserde.Deserialize(jsonData, newWorldLike(w))
+	assert.EqualError(t, err, "Components: entity 1 {3 0}: arkserde_test.Path: path without points")
+	decodeErr := &arkserde.DecodeError{}
+	assert.True(t, errors.As(err, &decodeErr))
+
+	calls := 0
+	err = arkserde.Deserialize(hooksData, newWorldLike(w),
+		arkserde.Opts.OnLoaded(func(world *ecs.World) error {
+			return fmt.Errorf("callback failed")
+		}),
+		arkserde.Opts.OnLoaded(func(world *ecs.World) error {
+			calls++
+			return nil
+		}),
+	)
+	assert.EqualError(t, err, "callback failed")
+	assert.Equal(t, 0, calls)
+}
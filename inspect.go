@@ -0,0 +1,191 @@
+package arkserde
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Info is a summary of serialized data, as returned by [Inspect].
+type Info struct {
+	Compressed    bool           // Whether the data is gzip-compressed.
+	Columnar      bool           // Whether the data is in the columnar layout.
+	FormatVersion int            // Version of the serialization format.
+	SchemaVersion int            // Version of the user's data schema.
+	Entities      int            // Number of entities in the entity pool, excluding reserved entities.
+	Alive         int            // Number of alive entities.
+	Dead          int            // Number of dead entities, available for recycling.
+	Types         []string       // Component types, as listed in the data.
+	TypeCounts    map[string]int // Number of entities with each component type.
+	Resources     []string       // Resource types, sorted by name.
+	Sections      []Section      // Top-level sections, in the order of the data.
+}
+
+// Section is a top-level section of serialized data.
+type Section struct {
+	Name string // Name of the section, like "World" or "Components".
+	Size int    // Size of the section's JSON value in bytes, uncompressed.
+}
+
+// Inspect reads a summary of data created by [Serialize] or [SerializeTo], without deserializing it.
+//
+// No world is required, and component and resource types do not need to be registered.
+// Compressed data is detected automatically.
+// With [Options.Compress], data that is not compressed results in an error.
+// Other options have no effect.
+func Inspect(data []byte, options ...Option) (Info, error) {
+	opts := newSerdeOptions(options...)
+
+	info := Info{TypeCounts: map[string]int{}}
+
+	var err error
+	data, info.Compressed, err = inspectData(data, &opts)
+	if err != nil {
+		return Info{}, err
+	}
+
+	sections, err := inspectSections(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	for _, section := range sections {
+		info.Sections = append(info.Sections, Section{Name: section.name, Size: len(section.value.Bytes)})
+	}
+
+	deserial := deserializer{}
+	if err := json.Unmarshal(data, &deserial); err != nil {
+		return Info{}, err
+	}
+
+	info.FormatVersion = deserial.Version.Format
+	info.SchemaVersion = deserial.Version.Schema
+	info.Columnar = deserial.Archetypes != nil
+
+	if len(deserial.World.Entities) > reservedEntities {
+		info.Entities = len(deserial.World.Entities) - reservedEntities
+	}
+	info.Alive = len(deserial.World.Alive)
+	info.Dead = max(info.Entities-info.Alive, 0)
+
+	info.Types = deserial.Types
+	if info.Types == nil {
+		info.Types = []string{}
+	}
+	for _, name := range info.Types {
+		info.TypeCounts[name] = 0
+	}
+
+	for i, comps := range deserial.Components {
+		mp := map[string]entry{}
+		if err := json.Unmarshal(comps.Bytes, &mp); err != nil {
+			return Info{}, fmt.Errorf("entity %d: %w", i, err)
+		}
+		for name := range mp {
+			if !strings.HasSuffix(name, targetTag) {
+				info.TypeCounts[name]++
+			}
+		}
+	}
+	for _, arch := range deserial.Archetypes {
+		for name := range arch.Columns {
+			info.TypeCounts[name] += len(arch.Entities)
+		}
+	}
+
+	info.Resources = sortedKeys(deserial.Resources)
+
+	return info, nil
+}
+
+// InspectEntities reads the components of all alive entities from data created by [Serialize] or [SerializeTo],
+// without deserializing it.
+//
+// Returns the compacted JSON of each component, by entity and type name.
+// Relation targets are included under the type name with the suffix ".ark.relation.Target".
+// Like for [Inspect], no world is required, and compressed data is detected automatically.
+// Errors in the data are returned as [*DecodeError].
+func InspectEntities(data []byte, options ...Option) (map[ecs.Entity]map[string][]byte, error) {
+	opts := newSerdeOptions(options...)
+
+	data, _, err := inspectData(data, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	deserial := deserializer{}
+	if err := json.Unmarshal(data, &deserial); err != nil {
+		return nil, newDecodeError("", -1, "", err)
+	}
+	entities, err := entityComponents(&deserial)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[ecs.Entity]map[string][]byte, len(entities))
+	for entity, comps := range entities {
+		mp := make(map[string][]byte, len(comps))
+		for name, value := range comps {
+			buffer := bytes.Buffer{}
+			if err := json.Compact(&buffer, value.Bytes); err != nil {
+				return nil, &DecodeError{Section: "Components", Index: -1, Entity: entity, Type: name, Err: err}
+			}
+			mp[name] = buffer.Bytes()
+		}
+		result[entity] = mp
+	}
+	return result, nil
+}
+
+// inspectData uncompresses data if it is gzip-compressed, and checks that it is not in the binary format.
+func inspectData(data []byte, opts *serdeOptions) ([]byte, bool, error) {
+	compressed := false
+	if isGZip(data) {
+		var err error
+		data, err = uncompressGZip(data)
+		if err != nil {
+			return nil, false, err
+		}
+		compressed = true
+	} else if opts.compressed {
+		return nil, false, fmt.Errorf("data is not gzip-compressed")
+	}
+	if bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return nil, false, fmt.Errorf("can't inspect data in the binary format")
+	}
+	return data, compressed, nil
+}
+
+// section is a top-level section of serialized data, with its raw JSON value.
+type section struct {
+	name  string
+	value entry
+}
+
+// inspectSections returns the top-level sections of a JSON document, in their order.
+func inspectSections(data []byte) ([]section, error) {
+	dec := newStreamDecoder(bytes.NewReader(data))
+	if err := dec.expectDelim('{'); err != nil {
+		return nil, err
+	}
+
+	sections := []section{}
+	for dec.More() {
+		key, err := dec.key()
+		if err != nil {
+			return nil, err
+		}
+		value := entry{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		sections = append(sections, section{name: key, value: value})
+	}
+	if err := dec.expectDelim('}'); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
@@ -0,0 +1,114 @@
+package arkserde_test
+
+import (
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.AddResource(w, &Velocity{X: 1})
+	ecs.AddResource(w, &Position{X: 1})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+		{arkserde.Opts.Compress()},
+	} {
+		jsonData, err := arkserde.Serialize(w, append(opts, arkserde.Opts.SchemaVersion(2))...)
+		assert.Nil(t, err)
+
+		// No options are required, even for compressed data.
+		info, err := arkserde.Inspect(jsonData)
+		assert.Nil(t, err)
+
+		assert.Equal(t, arkserde.FormatVersion, info.FormatVersion)
+		assert.Equal(t, 2, info.SchemaVersion)
+		assert.Equal(t, 32, info.Entities)
+		assert.Equal(t, 31, info.Alive)
+		assert.Equal(t, 1, info.Dead)
+		assert.Equal(t, []string{"arkserde_test.Position", "arkserde_test.ChildOf", "arkserde_test.ChildRelation", "arkserde_test.Velocity"}, info.Types)
+		assert.Equal(t, map[string]int{
+			"arkserde_test.Position":      26,
+			"arkserde_test.ChildOf":       0,
+			"arkserde_test.ChildRelation": 14,
+			"arkserde_test.Velocity":      10,
+		}, info.TypeCounts)
+		assert.Equal(t, []string{"arkserde_test.Position", "arkserde_test.Velocity"}, info.Resources)
+
+		names := []string{}
+		for _, section := range info.Sections {
+			names = append(names, section.Name)
+			assert.Greater(t, section.Size, 0)
+		}
+		if info.Columnar {
+			assert.Equal(t, []string{"Version", "World", "Types", "Archetypes", "Resources"}, names)
+		} else {
+			assert.Equal(t, []string{"Version", "World", "Types", "Components", "Resources"}, names)
+		}
+	}
+}
+
+func TestInspectErrors(t *testing.T) {
+	w := createArchetypesWorld()
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	_, err = arkserde.Inspect(jsonData, arkserde.Opts.Compress())
+	assert.EqualError(t, err, "data is not gzip-compressed")
+
+	_, err = arkserde.Inspect(jsonData[:len(jsonData)/2])
+	assert.NotNil(t, err)
+
+	_, err = arkserde.Inspect([]byte("[]"))
+	assert.NotNil(t, err)
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+	_, err = arkserde.Inspect(data)
+	assert.EqualError(t, err, "can't inspect data in the binary format")
+
+	_, err = arkserde.Inspect([]byte(textErrComponent))
+	assert.ErrorContains(t, err, "entity 0:")
+}
+
+func TestInspectEntities(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	child := ecs.NewMap2[Position, ChildRelation](w).NewEntity(&Position{X: 2, Y: 3}, &ChildRelation{Dummy: 4}, ecs.RelIdx(1, parent))
+
+	var expected map[ecs.Entity]map[string][]byte
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+		{arkserde.Opts.Compress()},
+	} {
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		entities, err := arkserde.InspectEntities(jsonData)
+		assert.Nil(t, err)
+		assert.Len(t, entities, countAll(w))
+
+		parentJSON, err := parent.MarshalJSON()
+		assert.Nil(t, err)
+		assert.Equal(t, map[string][]byte{
+			"arkserde_test.Position":                          []byte(`{"X":2,"Y":3}`),
+			"arkserde_test.ChildRelation":                     []byte(`{"Dummy":4}`),
+			"arkserde_test.ChildRelation.ark.relation.Target": parentJSON,
+		}, entities[child])
+
+		// Both layouts give the same result.
+		if expected == nil {
+			expected = entities
+		}
+		assert.Equal(t, expected, entities)
+	}
+
+	_, err := arkserde.InspectEntities([]byte(textErrComponent))
+	assert.ErrorContains(t, err, "Components: entity 0")
+}
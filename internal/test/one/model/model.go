@@ -0,0 +1,9 @@
+// Package model provides types for testing name collisions.
+// It has the same name as package two/model.
+package model
+
+// Position component.
+type Position struct {
+	X float64
+	Y float64
+}
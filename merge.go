@@ -0,0 +1,178 @@
+package arkserde
+
+import (
+	"reflect"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+var entityType = reflect.TypeFor[ecs.Entity]()
+
+// Merge deserializes JSON data into an Ark [ecs.World] that may already contain entities.
+//
+// In contrast to [Deserialize], the saved entity pool is not restored.
+// Instead, a new entity is created for each serialized entity.
+// Relation targets, and [ecs.Entity] values in components and resources, are rewritten to the new entities.
+// References to entities that are not contained in the data are set to the zero entity.
+//
+// Returns a map from serialized (old) to new entities.
+//
+// Data in both layouts and all options are supported, like for [Deserialize].
+// Resources contained in the data overwrite the world's resources.
+// Use [Options.SkipAllResources] to only add entities.
+// On error, the world is left unchanged, like for [Deserialize].
+func Merge(jsonData []byte, world *ecs.World, options ...Option) (map[ecs.Entity]ecs.Entity, error) {
+	opts := newSerdeOptions(options...)
+
+	remap, err := merge(jsonData, world, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return remap.entities, nil
+}
+
+func merge(jsonData []byte, world *ecs.World, opts *serdeOptions) (*entityRemap, error) {
+	deserial, err := unmarshalDocument(jsonData, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	remap := newEntityRemap()
+	if err := deserializeData(world, deserial, opts, remap); err != nil {
+		return nil, err
+	}
+
+	return remap, nil
+}
+
+// unmarshalDocument uncompresses and unmarshals JSON data, and checks its version.
+func unmarshalDocument(jsonData []byte, opts *serdeOptions) (*deserializer, error) {
+	if opts.compressed {
+		var err error
+		jsonData, err = uncompressGZip(jsonData)
+		if err != nil {
+			return nil, newDecodeError("", -1, "", err)
+		}
+	}
+
+	deserial := deserializer{}
+	if err := json.Unmarshal(jsonData, &deserial); err != nil {
+		return nil, newDecodeError("", -1, "", err)
+	}
+
+	if err := checkVersion(&deserial.Version, opts); err != nil {
+		return nil, newDecodeError("Version", -1, "", err)
+	}
+	return &deserial, nil
+}
+
+// entityRemap maps serialized entities to new entities, for merging into a non-empty world.
+type entityRemap struct {
+	entities map[ecs.Entity]ecs.Entity
+	types    entityTypes
+}
+
+func newEntityRemap() *entityRemap {
+	return &entityRemap{
+		entities: map[ecs.Entity]ecs.Entity{},
+		types:    entityTypes{},
+	}
+}
+
+// entity returns the new entity for a serialized entity.
+// Returns the entity unchanged if the remap is nil, and the zero entity if it is not mapped.
+func (r *entityRemap) entity(entity ecs.Entity) ecs.Entity {
+	if r == nil {
+		return entity
+	}
+	return r.entities[entity]
+}
+
+// value rewrites all entities in an addressable value.
+// Has no effect if the remap is nil.
+func (r *entityRemap) value(v reflect.Value) {
+	if r == nil {
+		return
+	}
+	walkEntities(v, r.types, func(e *ecs.Entity) {
+		*e = r.entity(*e)
+	})
+}
+
+// walkEntities calls fn for all entities in an addressable value, and reports whether fn changed any of them.
+// Entities in map values can be modified, while map keys and interfaces are not considered.
+// Map values are only written back if they were changed, so that read-only walks never modify the value.
+func walkEntities(v reflect.Value, types entityTypes, fn func(e *ecs.Entity)) bool {
+	if !types.contains(v.Type()) {
+		return false
+	}
+
+	changed := false
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == entityType {
+			e := v.Addr().Interface().(*ecs.Entity)
+			old := *e
+			fn(e)
+			return *e != old
+		}
+		for i := range v.NumField() {
+			if field := v.Field(i); field.CanSet() {
+				changed = walkEntities(field, types, fn) || changed
+			}
+		}
+	case reflect.Array, reflect.Slice:
+		for i := range v.Len() {
+			changed = walkEntities(v.Index(i), types, fn) || changed
+		}
+	case reflect.Pointer:
+		if !v.IsNil() {
+			changed = walkEntities(v.Elem(), types, fn)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(iter.Value())
+			if walkEntities(elem, types, fn) {
+				v.SetMapIndex(iter.Key(), elem)
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// entityTypes caches whether types contain entities.
+type entityTypes map[reflect.Type]bool
+
+// contains checks whether values of a type may contain entities.
+// Map keys and interfaces are not considered.
+func (t entityTypes) contains(tp reflect.Type) bool {
+	if contains, ok := t[tp]; ok {
+		return contains
+	}
+	// Prevents infinite recursion for recursive types.
+	t[tp] = false
+
+	contains := false
+	switch tp.Kind() {
+	case reflect.Struct:
+		if tp == entityType {
+			contains = true
+			break
+		}
+		for i := range tp.NumField() {
+			if field := tp.Field(i); field.IsExported() && t.contains(field.Type) {
+				contains = true
+				break
+			}
+		}
+	case reflect.Array, reflect.Slice, reflect.Pointer, reflect.Map:
+		contains = t.contains(tp.Elem())
+	}
+
+	t[tp] = contains
+	return contains
+}
@@ -0,0 +1,138 @@
+package arkserde_test
+
+import (
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+type Targets struct {
+	Entities []ecs.Entity
+	ByName   map[string]ecs.Entity
+	Next     *ecs.Entity
+}
+
+type Selected struct {
+	Entity ecs.Entity
+}
+
+// newMergeTarget creates a world like w, that already contains alive and dead entities.
+func newMergeTarget(w *ecs.World) *ecs.World {
+	w2 := newWorldLike(w)
+
+	posMap := ecs.NewMap1[Position](w2)
+	for i := range 5 {
+		posMap.NewEntity(&Position{X: float64(i)})
+	}
+	w2.RemoveEntity(posMap.NewEntity(&Position{}))
+
+	return w2
+}
+
+func TestMerge(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		w := createWorld(false)
+		posMap := ecs.NewMap1[Position](w)
+		parent := posMap.NewEntity(&Position{X: 1, Y: 2})
+		w.RemoveEntity(posMap.NewEntity(&Position{}))
+		child := ecs.NewMap2[Position, ChildOf](w).NewEntity(&Position{X: 3, Y: 4}, &ChildOf{Entity: parent})
+		rel := ecs.NewMap2[Position, ChildRelation](w).NewEntity(&Position{X: 5, Y: 6}, &ChildRelation{Dummy: 7}, ecs.RelIdx(1, parent))
+		next := child
+		targets := ecs.NewMap1[Targets](w).NewEntity(&Targets{
+			Entities: []ecs.Entity{parent, child, {}},
+			ByName:   map[string]ecs.Entity{"rel": rel},
+			Next:     &next,
+		})
+		ecs.AddResource(w, &Selected{Entity: child})
+
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := newMergeTarget(w)
+		mapping, err := arkserde.Merge(jsonData, w2)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 4, len(mapping))
+		assert.Equal(t, 9, countAll(w2))
+		for old, entity := range mapping {
+			assert.True(t, w2.Alive(entity))
+			assert.NotEqual(t, old, entity)
+		}
+
+		assert.Equal(t, Position{X: 1, Y: 2}, *ecs.NewMap[Position](w2).Get(mapping[parent]))
+		assert.Equal(t, ChildOf{Entity: mapping[parent]}, *ecs.NewMap[ChildOf](w2).Get(mapping[child]))
+
+		relMap := ecs.NewMap[ChildRelation](w2)
+		assert.Equal(t, ChildRelation{Dummy: 7}, *relMap.Get(mapping[rel]))
+		assert.Equal(t, mapping[parent], relMap.GetRelation(mapping[rel]))
+
+		tg := ecs.NewMap[Targets](w2).Get(mapping[targets])
+		assert.Equal(t, []ecs.Entity{mapping[parent], mapping[child], {}}, tg.Entities)
+		assert.Equal(t, map[string]ecs.Entity{"rel": mapping[rel]}, tg.ByName)
+		assert.Equal(t, mapping[child], *tg.Next)
+
+		assert.Equal(t, Selected{Entity: mapping[child]}, *ecs.GetResource[Selected](w2))
+
+		// Merge the same data a second time.
+		mapping2, err := arkserde.Merge(jsonData, w2, arkserde.Opts.SkipAllResources())
+		assert.Nil(t, err)
+		assert.Equal(t, 13, countAll(w2))
+		assert.Equal(t, mapping2[parent], relMap.GetRelation(mapping2[rel]))
+		assert.Equal(t, mapping[parent], relMap.GetRelation(mapping[rel]))
+		assert.Equal(t, Selected{Entity: mapping[child]}, *ecs.GetResource[Selected](w2))
+	}
+}
+
+func TestMergeSkip(t *testing.T) {
+	w := createArchetypesWorld()
+	selected := w.NewEntity()
+	ecs.AddResource(w, &Selected{Entity: selected})
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	w2 := newMergeTarget(w)
+	mapping, err := arkserde.Merge(jsonData, w2,
+		arkserde.Opts.Compress(),
+		arkserde.Opts.SkipComponents(ecs.C[ChildRelation]()),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, countAll(w)+5, countAll(w2))
+	assert.Equal(t, 0, countEntities[ChildRelation](w2))
+	assert.Equal(t, Selected{Entity: mapping[selected]}, *ecs.GetResource[Selected](w2))
+
+	w2 = newMergeTarget(w)
+	mapping, err = arkserde.Merge(jsonData, w2,
+		arkserde.Opts.Compress(),
+		arkserde.Opts.SkipEntities(),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mapping))
+	assert.Equal(t, 5, countAll(w2))
+	// The selected entity is not contained in the data.
+	assert.Equal(t, Selected{}, *ecs.GetResource[Selected](w2))
+}
+
+func TestMergeErrors(t *testing.T) {
+	w := createArchetypesWorld()
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w2 := ecs.NewWorld(1024)
+	w2.NewEntity()
+	_, err = arkserde.Merge(jsonData, w2)
+	assert.EqualError(t, err, "Types: arkserde_test.Position: component type is not registered")
+	assert.Equal(t, 1, countAll(w2))
+
+	_, err = arkserde.Merge([]byte("{"), newMergeTarget(w))
+	assert.NotNil(t, err)
+
+	_, err = arkserde.Merge(jsonData, newMergeTarget(w), arkserde.Opts.Compress())
+	assert.NotNil(t, err)
+}
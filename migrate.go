@@ -0,0 +1,54 @@
+package arkserde
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FormatVersion is the version of the serialization format written by this package.
+//
+// Data without version information is treated as format version 0,
+// which is read like version 1.
+const FormatVersion = 1
+
+// Migration rewrites the JSON of a component or resource from one schema version to the next.
+// See [Options.Migrate].
+type Migration func(jsonData []byte) ([]byte, error)
+
+// version of serialized data.
+type version struct {
+	Format int // Version of the serialization format.
+	Schema int // Version of the user's data schema.
+}
+
+// checkVersion checks that data of the given version can be read with the given options.
+func checkVersion(v *version, opts *serdeOptions) error {
+	if v.Format > FormatVersion {
+		return fmt.Errorf("unsupported format version %d, supports up to version %d", v.Format, FormatVersion)
+	}
+	if v.Schema > opts.schemaVersion {
+		return fmt.Errorf("data has schema version %d, but the current schema version is %d", v.Schema, opts.schemaVersion)
+	}
+	return nil
+}
+
+// migrate the JSON of a component or resource of the given type
+// from the given schema version to the current schema version.
+func migrate(tp reflect.Type, schema int, jsonData []byte, opts *serdeOptions) ([]byte, error) {
+	migrations, ok := opts.migrations[tp]
+	if !ok {
+		return jsonData, nil
+	}
+	for v := schema; v < opts.schemaVersion; v++ {
+		fn, ok := migrations[v]
+		if !ok {
+			continue
+		}
+		var err error
+		jsonData, err = fn(jsonData)
+		if err != nil {
+			return nil, fmt.Errorf("migrating %s from schema version %d: %w", tp.String(), v, err)
+		}
+	}
+	return jsonData, nil
+}
@@ -0,0 +1,156 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeVersion(t *testing.T) {
+	jsonData, _, _, err := serialize()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(jsonData), "{\n\"Version\" : {\"Format\":1,\"Schema\":0},\n"))
+
+	jsonData, _, _, err = serialize(arkserde.Opts.SchemaVersion(3))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(jsonData), "{\n\"Version\" : {\"Format\":1,\"Schema\":3},\n"))
+
+	w := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w)
+	_ = ecs.ComponentID[Velocity](w)
+	_ = ecs.ComponentID[ChildOf](w)
+	ecs.AddResource(w, &Position{})
+	ecs.AddResource(w, &Velocity{})
+
+	err = arkserde.Deserialize(jsonData, w)
+	assert.EqualError(t, err, "Version: data has schema version 3, but the current schema version is 0")
+
+	err = arkserde.Deserialize(jsonData, w, arkserde.Opts.SchemaVersion(3))
+	assert.Nil(t, err)
+}
+
+func TestDeserializeMigrate(t *testing.T) {
+	// Version 0 -> 1: fields were renamed
+	renameFields := func(jsonData []byte) ([]byte, error) {
+		str := strings.ReplaceAll(string(jsonData), "PosX", "X")
+		str = strings.ReplaceAll(str, "PosY", "Y")
+		return []byte(str), nil
+	}
+	// Version 1 -> 2: values were scaled
+	scale := func(jsonData []byte) ([]byte, error) {
+		pos := Position{}
+		if err := json.Unmarshal(jsonData, &pos); err != nil {
+			return nil, err
+		}
+		pos.X *= 10
+		pos.Y *= 10
+		return json.Marshal(&pos)
+	}
+
+	options := []arkserde.Option{
+		arkserde.Opts.SchemaVersion(2),
+		arkserde.Opts.Migrate(ecs.C[Position](), 0, renameFields),
+		arkserde.Opts.Migrate(ecs.C[Position](), 1, scale),
+	}
+
+	for _, stream := range []bool{false, true} {
+		w := ecs.NewWorld(1024)
+		posMap := ecs.NewMap[Position](w)
+		pos := &Position{}
+		ecs.AddResource(w, pos)
+
+		if stream {
+			err := arkserde.DeserializeFrom(bytes.NewReader([]byte(textMigrate)), w, options...)
+			assert.Nil(t, err)
+		} else {
+			err := arkserde.Deserialize([]byte(textMigrate), w, options...)
+			assert.Nil(t, err)
+		}
+
+		query := ecs.NewUnsafeFilter(w).Query()
+		assert.Equal(t, 2, query.Count())
+		query.Next()
+		assert.Equal(t, Position{X: 10, Y: 20}, *posMap.Get(query.Entity()))
+		query.Next()
+		assert.Equal(t, Position{X: 30, Y: 40}, *posMap.Get(query.Entity()))
+		query.Close()
+
+		assert.Equal(t, Position{X: 1000, Y: 0}, *pos)
+	}
+
+	// Data already in version 1 is only migrated once.
+	w := ecs.NewWorld(1024)
+	posMap := ecs.NewMap[Position](w)
+	ecs.AddResource(w, &Position{})
+	err := arkserde.Deserialize([]byte(strings.ReplaceAll(textMigrate, `"Schema":0`, `"Schema":1`)), w, options...)
+	assert.Nil(t, err)
+	query := ecs.NewUnsafeFilter(w).Query()
+	query.Next()
+	assert.Equal(t, Position{X: 0, Y: 0}, *posMap.Get(query.Entity()))
+	query.Close()
+}
+
+func TestDeserializeMigrateErrors(t *testing.T) {
+	createWorld := func() *ecs.World {
+		w := ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Position](w)
+		ecs.AddResource(w, &Position{})
+		return w
+	}
+	fail := func(jsonData []byte) ([]byte, error) {
+		return nil, errors.New("migration failed")
+	}
+
+	err := arkserde.Deserialize([]byte(textMigrate), createWorld(),
+		arkserde.Opts.SchemaVersion(1), arkserde.Opts.Migrate(ecs.C[Position](), 0, fail))
+	assert.EqualError(t, err, "Components: entity 0 {2 0}: arkserde_test.Position: migrating arkserde_test.Position from schema version 0: migration failed")
+
+	err = arkserde.Deserialize([]byte(textMigrate), createWorld(),
+		arkserde.Opts.SchemaVersion(1), arkserde.Opts.SkipEntities(), arkserde.Opts.Migrate(ecs.C[Position](), 0, fail))
+	assert.EqualError(t, err, "Resources: arkserde_test.Position: migrating arkserde_test.Position from schema version 0: migration failed")
+
+	newFormat := strings.ReplaceAll(textMigrate, `"Format":1`, `"Format":1000`)
+	err = arkserde.Deserialize([]byte(newFormat), createWorld())
+	assert.EqualError(t, err, fmt.Sprintf("Version: unsupported format version 1000, supports up to version %d", arkserde.FormatVersion))
+
+	err = arkserde.DeserializeFrom(bytes.NewReader([]byte(newFormat)), createWorld())
+	assert.EqualError(t, err, fmt.Sprintf("Version: unsupported format version 1000, supports up to version %d", arkserde.FormatVersion))
+
+	err = arkserde.DeserializeFrom(bytes.NewReader([]byte(textErrVersionOrder)), createWorld())
+	assert.EqualError(t, err, "section Version must be the first section")
+
+	assert.PanicsWithValue(t, "schema version must not be negative", func() { arkserde.Opts.SchemaVersion(-1) })
+	assert.PanicsWithValue(t, "schema version must not be negative", func() { arkserde.Opts.Migrate(ecs.C[Position](), -1, fail) })
+}
+
+const textMigrate = `{
+	"Version" : {"Format":1,"Schema":0},
+	"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,3],"Next":0,"Available":0},
+	"Types" : [
+	  "arkserde_test.Position"
+	],
+	"Components" : [
+	  {
+		"arkserde_test.Position" : {"PosX":1,"PosY":2}
+	  },
+	  {
+		"arkserde_test.Position" : {"PosX":3,"PosY":4}
+	  }
+	],
+	"Resources" : {
+		"arkserde_test.Position" : {"PosX":100,"PosY":0}
+	}}`
+
+const textErrVersionOrder = `{
+	"World" : {"Entities":[[0,4294967295],[1,4294967295]],"Alive":[],"Next":0,"Available":0},
+	"Version" : {"Format":1,"Schema":0},
+	"Types" : [],
+	"Components" : [],
+	"Resources" : {}}`
@@ -45,6 +45,21 @@ func (o Options) Compress(level ...int) Option {
 	}
 }
 
+// Columnar writes entities grouped by archetype, instead of one object per entity.
+//
+// Each archetype is a group of entities with the same components and relation targets.
+// It is written as a list of entity indices, the relation targets,
+// and one array (column) of values per component.
+// This results in considerably smaller files, and faster deserialization.
+//
+// Data in both layouts can be deserialized without the option.
+// Has no effect when deserializing.
+func (o Options) Columnar() Option {
+	return func(o *serdeOptions) {
+		o.columnar = true
+	}
+}
+
 // SkipAllResources skips serialization or de-serialization of all resources.
 func (o Options) SkipAllResources() Option {
 	return func(o *serdeOptions) {
@@ -69,6 +84,7 @@ func (o Options) SkipEntities() Option {
 // SkipComponents skips serialization or de-serialization of certain components.
 //
 // When deserializing, the skipped components must still be registered.
+// For types that are never serialized, see [Transient].
 func (o Options) SkipComponents(comps ...ecs.Comp) Option {
 	return func(o *serdeOptions) {
 		o.skipComponents = make([]reflect.Type, len(comps))
@@ -81,6 +97,7 @@ func (o Options) SkipComponents(comps ...ecs.Comp) Option {
 // SkipResources skips serialization or de-serialization of certain resources.
 //
 // When deserializing, the skipped resources must still be registered.
+// For types that are never serialized, see [Transient].
 func (o Options) SkipResources(comps ...ecs.Comp) Option {
 	return func(o *serdeOptions) {
 		o.skipResources = make([]reflect.Type, len(comps))
@@ -90,6 +107,225 @@ func (o Options) SkipResources(comps ...ecs.Comp) Option {
 	}
 }
 
+// IncludeComponents serializes or de-serializes only the given components.
+// It is the inverse of [Options.SkipComponents], and can be combined with it.
+//
+// All entities are still serialized, also those without any of the given components.
+// See [Options.With] for serializing only entities with certain components.
+// When deserializing, components of other types are skipped, and don't need to be registered.
+func (o Options) IncludeComponents(comps ...ecs.Comp) Option {
+	return func(o *serdeOptions) {
+		o.includeComponents = make([]reflect.Type, len(comps))
+		for i, c := range comps {
+			o.includeComponents[i] = c.Type()
+		}
+	}
+}
+
+// IncludeResources serializes or de-serializes only the given resources.
+// It is the inverse of [Options.SkipResources], and can be combined with it.
+//
+// When deserializing, resources of other types are skipped, and don't need to be registered.
+func (o Options) IncludeResources(comps ...ecs.Comp) Option {
+	return func(o *serdeOptions) {
+		o.includeResources = make([]reflect.Type, len(comps))
+		for i, c := range comps {
+			o.includeResources[i] = c.Type()
+		}
+	}
+}
+
+// With serializes only entities that have all of the given components.
+//
+// Entities that are not serialized are written as if they were removed from the world.
+// Relation targets pointing to them are written as the zero entity,
+// while entities stored in components are kept as they are.
+// Deserialization of the resulting data requires no special options.
+//
+// Can be combined with [Options.Without] and [Options.OnlyEntities].
+// Has no effect when deserializing.
+func (o Options) With(comps ...ecs.Comp) Option {
+	return func(o *serdeOptions) {
+		for _, c := range comps {
+			o.with = append(o.with, c.Type())
+		}
+	}
+}
+
+// Without serializes only entities that have none of the given components.
+//
+// See [Options.With] for details.
+func (o Options) Without(comps ...ecs.Comp) Option {
+	return func(o *serdeOptions) {
+		for _, c := range comps {
+			o.without = append(o.without, c.Type())
+		}
+	}
+}
+
+// OnlyEntities serializes only the given entities.
+// Entities that are not alive are ignored.
+//
+// See [Options.With] for details.
+func (o Options) OnlyEntities(entities ...ecs.Entity) Option {
+	return func(o *serdeOptions) {
+		if o.onlyEntities == nil {
+			o.onlyEntities = map[ecs.Entity]struct{}{}
+		}
+		for _, e := range entities {
+			o.onlyEntities[e] = struct{}{}
+		}
+	}
+}
+
+// QualifiedNames identifies component and resource types by their full package path
+// instead of by the package name only.
+// E.g., a type Position in package github.com/user/sim/model is written as
+// "github.com/user/sim/model.Position" instead of "model.Position".
+// This prevents collisions between equally named types in different packages.
+//
+// Type arguments of generic types are always qualified by their full package path, as named by package [reflect].
+// E.g., a type Generic[model.Position] in package main is written as
+// "main.Generic[github.com/user/sim/model.Position]", with or without this option.
+// An error is returned if two types still have the same name.
+//
+// For serialized data created with this option,
+// the option must also be used for deserialization.
+func (o Options) QualifiedNames() Option {
+	return func(o *serdeOptions) {
+		o.qualifiedNames = true
+	}
+}
+
+// Alias adds an alternative name for a component or resource type, used when deserializing.
+// Use it to load data that was serialized before a type was renamed or moved to another package.
+//
+// The alias applies to the type name in the list of types,
+// to component and relation target keys of entities, and to resource keys.
+// The type needs to be registered as a component or resource, as usual.
+// Multiple aliases can be added, also for the same type.
+//
+//	arkserde.Opts.Alias("oldpkg.OldName", ecs.C[NewName]())
+//
+// Has no effect when serializing.
+func (o Options) Alias(name string, comp ecs.Comp) Option {
+	return func(o *serdeOptions) {
+		o.aliases = append(o.aliases, alias{name: name, tp: comp.Type()})
+	}
+}
+
+// SchemaVersion sets the version of the user's data schema.
+//
+// When serializing, the version is written to the output.
+// When deserializing, it is the version that data from older versions is migrated to.
+// Data with a higher schema version results in an error.
+// See [Options.Migrate] for registering migrations.
+//
+// The default schema version is 0.
+func (o Options) SchemaVersion(version int) Option {
+	if version < 0 {
+		panic("schema version must not be negative")
+	}
+	return func(o *serdeOptions) {
+		o.schemaVersion = version
+	}
+}
+
+// Migrate adds a migration for a component or resource type from schema version from to from+1.
+// See also [Options.SchemaVersion].
+//
+// When deserializing data with an older schema version than the current one,
+// the JSON of each component or resource of the given type is passed
+// through all migrations from the data's schema version up to the current schema version, in order.
+// Versions without a migration for the type are skipped.
+//
+// Has no effect when serializing.
+func (o Options) Migrate(comp ecs.Comp, from int, fn Migration) Option {
+	if from < 0 {
+		panic("schema version must not be negative")
+	}
+	return func(o *serdeOptions) {
+		if o.migrations == nil {
+			o.migrations = map[reflect.Type]map[int]Migration{}
+		}
+		migrations, ok := o.migrations[comp.Type()]
+		if !ok {
+			migrations = map[int]Migration{}
+			o.migrations[comp.Type()] = migrations
+		}
+		migrations[from] = fn
+	}
+}
+
+// Codec sets a custom encoder and decoder for a component or resource type,
+// used instead of the default JSON encoding.
+// Use it for types that can't be handled by the default encoding,
+// like types with interface or function fields.
+//
+// The encoder must produce valid JSON.
+// Both functions receive a pointer to a value of the type.
+// Migrations are applied to the encoded JSON before it is passed to the decoder.
+// With [SerializeBinary], types with a codec are always stored as JSON.
+func (o Options) Codec(comp ecs.Comp, encode Encoder, decode Decoder) Option {
+	return func(o *serdeOptions) {
+		if o.codecs == nil {
+			o.codecs = map[reflect.Type]codec{}
+		}
+		o.codecs[comp.Type()] = codec{encode: encode, decode: decode}
+	}
+}
+
+// UnknownTypes sets how deserialization handles component and resource types that are not registered,
+// neither in the world nor in the global registry. See [UnknownPolicy] for the available policies.
+//
+// By default, unknown types result in an error.
+// With [UnknownIgnore], their data is skipped.
+// With [UnknownPreserve], their data is kept in an [UnknownData] resource,
+// and written back by [Serialize], so that data of types unknown to a program is not lost.
+//
+// The binary format does not support [UnknownPreserve].
+// Has no effect when serializing.
+func (o Options) UnknownTypes(policy UnknownPolicy) Option {
+	return func(o *serdeOptions) {
+		o.unknownPolicy = policy
+	}
+}
+
+// OnLoaded adds a callback that is called after all data was loaded into the world,
+// e.g. to rebuild indices or other derived data.
+// Multiple callbacks are called in the order they were added,
+// after the hooks of components and resources (see [AfterDeserializer]).
+//
+// The first error returned by a callback is returned by the deserialization function.
+// In this case, the data was already added to the world.
+// Has no effect when serializing.
+func (o Options) OnLoaded(fn func(world *ecs.World) error) Option {
+	return func(o *serdeOptions) {
+		o.onLoaded = append(o.onLoaded, fn)
+	}
+}
+
+// Parallel encodes and decodes the components of entities on up to n goroutines.
+// Entities are processed in chunks, and the output is the same as without the option.
+// Use it to speed up the serialization and deserialization of large worlds.
+//
+// Applies to the default layout with [Serialize] and [SerializeTo],
+// and to [Deserialize], [Merge] and [ApplyDelta].
+// Has no effect for the columnar layout (see [Options.Columnar]), the binary format,
+// prefabs, and [DeserializeFrom].
+//
+// Hooks (see [BeforeSerializer]), codecs (see [Options.Codec]) and migrations (see [Options.Migrate])
+// of components may be called concurrently, for different entities.
+// A value of 1 disables parallel processing.
+func (o Options) Parallel(n int) Option {
+	if n < 1 {
+		panic("number of goroutines must be positive")
+	}
+	return func(o *serdeOptions) {
+		o.parallel = n
+	}
+}
+
 type serdeOptions struct {
 	skipAllResources  bool
 	skipAllComponents bool
@@ -98,8 +334,73 @@ type serdeOptions struct {
 	compressed       bool
 	compressionLevel int
 
+	qualifiedNames bool
+	columnar       bool
+
 	skipComponents []reflect.Type
 	skipResources  []reflect.Type
+
+	includeComponents []reflect.Type // Nil if all components are included.
+	includeResources  []reflect.Type // Nil if all resources are included.
+
+	with         []reflect.Type
+	without      []reflect.Type
+	onlyEntities map[ecs.Entity]struct{}
+
+	aliases []alias
+
+	schemaVersion int
+	migrations    map[reflect.Type]map[int]Migration
+
+	codecs map[reflect.Type]codec
+
+	unknownPolicy UnknownPolicy
+
+	onLoaded []func(world *ecs.World) error
+
+	parallel int // Number of goroutines for encoding and decoding components. Sequential if less than 2.
+}
+
+// hasFilter checks whether only a subset of entities is serialized.
+func (o *serdeOptions) hasFilter() bool {
+	return len(o.with) > 0 || len(o.without) > 0 || o.onlyEntities != nil
+}
+
+// componentPolicy returns the policy for a component type name that is not registered.
+// Types that are not included by [Options.IncludeComponents] are ignored.
+func (o *serdeOptions) componentPolicy(name string) UnknownPolicy {
+	if o.excludes(o.includeComponents, name) {
+		return UnknownIgnore
+	}
+	return o.unknownPolicy
+}
+
+// resourcePolicy returns the policy for a resource type name that is not registered.
+// Types that are not included by [Options.IncludeResources] are ignored.
+func (o *serdeOptions) resourcePolicy(name string) UnknownPolicy {
+	if o.excludes(o.includeResources, name) {
+		return UnknownIgnore
+	}
+	return o.unknownPolicy
+}
+
+// excludes checks whether a type name is not in the given list of included types.
+// Nothing is excluded if the list is nil.
+func (o *serdeOptions) excludes(include []reflect.Type, name string) bool {
+	if include == nil {
+		return false
+	}
+	for _, tp := range include {
+		if typeName(tp, o) == name {
+			return false
+		}
+	}
+	return true
+}
+
+type alias struct {
+	name string
+	tp   reflect.Type
 }
 
 func newSerdeOptions(opts ...Option) serdeOptions {
@@ -18,6 +18,19 @@ func TestOptions(t *testing.T) {
 		Opts.SkipComponents(ecs.C[testComp]()),
 		Opts.SkipResources(ecs.C[testComp]()),
 		Opts.Compress(8),
+		Opts.QualifiedNames(),
+		Opts.Alias("a", ecs.C[testComp]()),
+		Opts.Alias("b", ecs.C[testComp]()),
+		Opts.SchemaVersion(2),
+		Opts.Migrate(ecs.C[testComp](), 0, func(b []byte) ([]byte, error) { return b, nil }),
+		Opts.Migrate(ecs.C[testComp](), 1, func(b []byte) ([]byte, error) { return b, nil }),
+		Opts.With(ecs.C[testComp]()),
+		Opts.Without(ecs.C[testComp]()),
+		Opts.OnlyEntities(ecs.Entity{}),
+		Opts.Codec(ecs.C[testComp](),
+			func(v interface{}) ([]byte, error) { return []byte("{}"), nil },
+			func(b []byte, v interface{}) error { return nil }),
+		Opts.Parallel(4),
 	)
 
 	assert.True(t, opt.skipEntities)
@@ -28,6 +41,20 @@ func TestOptions(t *testing.T) {
 
 	assert.True(t, opt.compressed)
 	assert.Equal(t, 8, opt.compressionLevel)
+	assert.True(t, opt.qualifiedNames)
+	assert.Equal(t, []alias{
+		{name: "a", tp: ecs.C[testComp]().Type()},
+		{name: "b", tp: ecs.C[testComp]().Type()},
+	}, opt.aliases)
+	assert.Equal(t, 2, opt.schemaVersion)
+	assert.Len(t, opt.migrations[ecs.C[testComp]().Type()], 2)
+	assert.Equal(t, []reflect.Type{ecs.C[testComp]().Type()}, opt.with)
+	assert.Equal(t, []reflect.Type{ecs.C[testComp]().Type()}, opt.without)
+	assert.Len(t, opt.onlyEntities, 1)
+	assert.True(t, opt.hasFilter())
+	assert.Len(t, opt.codecs, 1)
+	assert.Equal(t, 4, opt.parallel)
 
 	assert.PanicsWithValue(t, "maximum one value allowed for compression level", func() { Opts.Compress(1, 2, 3) })
+	assert.PanicsWithValue(t, "number of goroutines must be positive", func() { Opts.Parallel(0) })
 }
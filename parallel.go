@@ -0,0 +1,52 @@
+package arkserde
+
+import (
+	"sync"
+)
+
+// parallelChunkSize is the number of entities per chunk, for encoding and decoding with [Options.Parallel].
+const parallelChunkSize = 1024
+
+// runChunks splits count items into chunks of consecutive items, and calls fn for each chunk.
+// Chunks are processed in rounds of one chunk per worker, with each chunk on its own goroutine.
+// The slot passed to fn is the index of the chunk in its round, for re-using per-worker resources.
+//
+// After each round, done is called for the slots of the round, in chunk order.
+// Done can be nil.
+//
+// Returns the error of the first chunk that failed, in chunk order.
+func runChunks(count int, workers int, fn func(slot, start, end int) error, done func(slot int) error) error {
+	numChunks := (count + parallelChunkSize - 1) / parallelChunkSize
+	errs := make([]error, workers)
+
+	for first := 0; first < numChunks; first += workers {
+		last := min(first+workers, numChunks)
+
+		wg := sync.WaitGroup{}
+		for chunk := first; chunk < last; chunk++ {
+			slot := chunk - first
+			start := chunk * parallelChunkSize
+			end := min(start+parallelChunkSize, count)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[slot] = fn(slot, start, end)
+			}()
+		}
+		wg.Wait()
+
+		for slot := range last - first {
+			if errs[slot] != nil {
+				return errs[slot]
+			}
+			if done == nil {
+				continue
+			}
+			if err := done(slot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
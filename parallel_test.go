@@ -0,0 +1,87 @@
+package arkserde_test
+
+import (
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeParallel(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Velocity](w).NewBatchFn(5000, func(e ecs.Entity, pos *Position, vel *Velocity) {
+		pos.X = float64(e.ID())
+		vel.Y = pos.X
+	})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.SkipAllComponents()},
+		{arkserde.Opts.With(ecs.C[Velocity]())},
+		{arkserde.Opts.SkipComponents(ecs.C[Position]())},
+	} {
+		expected, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		for _, n := range []int{1, 2, 3, 8} {
+			jsonData, err := arkserde.Serialize(w, append(opts, arkserde.Opts.Parallel(n))...)
+			assert.Nil(t, err)
+			assert.Equal(t, string(expected), string(jsonData))
+		}
+	}
+
+	// Small worlds fit into a single chunk.
+	w = createArchetypesWorld()
+	expected, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Parallel(4))
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(jsonData))
+}
+
+func TestDeserializeParallel(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Velocity](w).NewBatchFn(5000, func(e ecs.Entity, pos *Position, vel *Velocity) {
+		pos.X = float64(e.ID())
+		vel.Y = pos.X
+	})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.Deserialize(jsonData, w2, arkserde.Opts.Parallel(3))
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+
+	w3 := newWorldLike(w)
+	remap, err := arkserde.Merge(jsonData, w3, arkserde.Opts.Parallel(3))
+	assert.Nil(t, err)
+	assert.Len(t, remap, countAll(w))
+}
+
+func TestDeserializeParallelErrors(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.NewMap2[Position, Velocity](w).NewBatchFn(5000, func(e ecs.Entity, pos *Position, vel *Velocity) {
+		pos.X = float64(e.ID())
+		vel.Y = pos.X
+	})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	// Migrations fail for all entities, but the error of the first entity is returned.
+	opts := []arkserde.Option{
+		arkserde.Opts.SchemaVersion(1),
+		arkserde.Opts.Migrate(ecs.C[Velocity](), 0, func(jsonData []byte) ([]byte, error) {
+			return []byte("[]"), nil
+		}),
+		arkserde.Opts.SkipAllResources(),
+	}
+	expected := arkserde.Deserialize(jsonData, newWorldLike(w), opts...)
+	assert.NotNil(t, expected)
+
+	w2 := newWorldLike(w)
+	err = arkserde.Deserialize(jsonData, w2, append(opts, arkserde.Opts.Parallel(4))...)
+	assert.Equal(t, expected.Error(), err.Error())
+	assert.Equal(t, 0, countEntities[Position](w2))
+}
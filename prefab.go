@@ -0,0 +1,283 @@
+package arkserde
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"unsafe"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// reservedEntities is the number of reserved entities at the start of the entity pool.
+const reservedEntities = 2
+
+// SerializeEntities serializes the given root entities as a prefab,
+// together with all entities reachable from them through relation targets and [ecs.Entity] values in components.
+//
+// References between the entities are stored as local entities, in the order in which entities were collected.
+// The local entity of the entity at index i has ID i+2 (after the two reserved entities) and generation 0.
+// References to entities that are not alive are stored as the zero entity.
+//
+// Use [Instantiate] to create copies of the prefab in a world.
+// Only the component types of the serialized entities are written, and resources are not serialized.
+//
+// Supports options [Options.Compress], [Options.SkipComponents], [Options.IncludeComponents],
+// [Options.SkipAllComponents], [Options.QualifiedNames] and [Options.SchemaVersion].
+// Skipped components are not followed when collecting entities.
+func SerializeEntities(world *ecs.World, roots []ecs.Entity, options ...Option) ([]byte, error) {
+	opts := newSerdeOptions(options...)
+
+	for _, root := range roots {
+		if !world.Alive(root) {
+			return nil, fmt.Errorf("root entity %v is not alive", root)
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	if opts.compressed {
+		err := writeGZip(&buffer, opts.compressionLevel, func(w io.Writer) error {
+			return serializeEntities(w, world, roots, &opts)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	if err := serializeEntities(&buffer, world, roots, &opts); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Instantiate creates a copy of a prefab in the world, and returns the new root entities.
+//
+// Data must be created with [SerializeEntities].
+// Like for [Merge], the world may already contain entities,
+// and references between the entities of the prefab are rewritten to the new entities.
+// A prefab can be instantiated any number of times.
+//
+// The world must be prepared like for [Deserialize], except that it can contain entities.
+// Supports the same options as [Merge].
+// On error, the world is left unchanged.
+func Instantiate(jsonData []byte, world *ecs.World, options ...Option) ([]ecs.Entity, error) {
+	opts := newSerdeOptions(options...)
+
+	deserial, err := unmarshalDocument(jsonData, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Roots are checked before loading, to leave the world unchanged on error.
+	for _, idx := range deserial.Roots {
+		if idx < 0 || idx >= len(deserial.World.Alive) {
+			return nil, newDecodeError("Roots", -1, "", fmt.Errorf("root index %d out of range for %d entities", idx, len(deserial.World.Alive)))
+		}
+	}
+
+	remap := newEntityRemap()
+	if err := deserializeData(world, deserial, &opts, remap); err != nil {
+		return nil, err
+	}
+
+	roots := make([]ecs.Entity, len(deserial.Roots))
+	for i, idx := range deserial.Roots {
+		roots[i] = remap.entity(deserial.World.Entities[deserial.World.Alive[idx]])
+	}
+	return roots, nil
+}
+
+func serializeEntities(w io.Writer, world *ecs.World, roots []ecs.Entity, opts *serdeOptions) error {
+	writer := bufio.NewWriterSize(w, writeBufferSize)
+
+	skipComponents := skipMask(world, opts)
+	entities := collectEntities(world, roots, &skipComponents, opts)
+
+	// Maps entities of the world to local entities.
+	local := newEntityRemap()
+	dump := ecs.EntityDump{
+		Entities: make([]ecs.Entity, 0, len(entities)+reservedEntities),
+		Alive:    make([]uint32, 0, len(entities)),
+	}
+	for i := range reservedEntities {
+		dump.Entities = append(dump.Entities, newEntity(uint32(i), math.MaxUint32))
+	}
+	for i, entity := range entities {
+		id := uint32(i + reservedEntities)
+		local.entities[entity] = newEntity(id, 0)
+		dump.Entities = append(dump.Entities, local.entities[entity])
+		dump.Alive = append(dump.Alive, id)
+	}
+
+	writer.WriteString("{\n")
+
+	serializeVersion(writer, opts)
+	writer.WriteString(",\n")
+
+	if err := serializeWorld(&dump, writer, opts); err != nil {
+		return err
+	}
+	writer.WriteString(",\n")
+
+	// Only the types of the collected entities are written,
+	// so that the prefab does not require unrelated types in the target world.
+	used := usedComponents(world, entities, &skipComponents)
+	if err := serializeTypes(world, writer, opts, &used, nil); err != nil {
+		return err
+	}
+	writer.WriteString(",\n")
+
+	names := componentNames(world, opts)
+	u := world.Unsafe()
+
+	writer.WriteString("\"Components\" : [\n")
+	for i, entity := range entities {
+		writer.WriteString("  {")
+		if !opts.skipAllComponents {
+			ids := u.IDs(entity)
+			count := 0
+			for j := range ids.Len() {
+				id := ids.Get(j)
+				if skipComponents.Get(id) {
+					continue
+				}
+				info, _ := ecs.ComponentInfo(world, id)
+
+				if count > 0 {
+					writer.WriteString(",")
+				}
+				writer.WriteString("\n")
+				count++
+
+				if info.IsRelation {
+					target := local.entity(u.GetRelation(entity, id))
+					eJSON, err := target.MarshalJSON()
+					if err != nil {
+						return err
+					}
+					writer.WriteString("    \"")
+					writer.WriteString(names[id.Index()])
+					writer.WriteString(targetTag)
+					writer.WriteString("\" : ")
+					writer.Write(eJSON)
+					writer.WriteString(",\n")
+				}
+
+				jsonData, err := marshalLocal(info.Type, u.Get(entity, id), local, opts)
+				if err != nil {
+					return err
+				}
+				writer.WriteString("    \"")
+				writer.WriteString(names[id.Index()])
+				writer.WriteString("\" : ")
+				writer.Write(jsonData)
+			}
+			if count > 0 {
+				writer.WriteString("\n  ")
+			}
+		}
+		writer.WriteString("}")
+		if i < len(entities)-1 {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
+	}
+	writer.WriteString("],\n")
+
+	writer.WriteString("\"Roots\" : [")
+	for i, root := range roots {
+		if i > 0 {
+			writer.WriteByte(',')
+		}
+		writer.WriteString(strconv.Itoa(int(local.entity(root).ID()) - reservedEntities))
+	}
+	writer.WriteString("],\n")
+
+	writer.WriteString("\"Resources\" : {}\n")
+	writer.WriteString("}\n")
+
+	return writer.Flush()
+}
+
+// collectEntities collects the root entities and all entities reachable from them, in breadth-first order.
+func collectEntities(world *ecs.World, roots []ecs.Entity, skipComponents *bitMask, opts *serdeOptions) []ecs.Entity {
+	u := world.Unsafe()
+	types := entityTypes{}
+
+	entities := []ecs.Entity{}
+	visited := map[ecs.Entity]bool{}
+	add := func(e ecs.Entity) {
+		if e.IsZero() || visited[e] || !world.Alive(e) {
+			return
+		}
+		visited[e] = true
+		entities = append(entities, e)
+	}
+
+	for _, root := range roots {
+		add(root)
+	}
+	if opts.skipAllComponents {
+		return entities
+	}
+
+	for i := 0; i < len(entities); i++ {
+		entity := entities[i]
+		ids := u.IDs(entity)
+		for j := range ids.Len() {
+			id := ids.Get(j)
+			if skipComponents.Get(id) {
+				continue
+			}
+			info, _ := ecs.ComponentInfo(world, id)
+			if info.IsRelation {
+				add(u.GetRelation(entity, id))
+			}
+			if types.contains(info.Type) {
+				walkEntities(reflect.NewAt(info.Type, u.Get(entity, id)).Elem(), types, func(e *ecs.Entity) {
+					add(*e)
+				})
+			}
+		}
+	}
+	return entities
+}
+
+// usedComponents returns the IDs of all components of the given entities that are not skipped.
+func usedComponents(world *ecs.World, entities []ecs.Entity, skipComponents *bitMask) bitMask {
+	u := world.Unsafe()
+	used := bitMask{}
+	for _, entity := range entities {
+		ids := u.IDs(entity)
+		for j := range ids.Len() {
+			id := ids.Get(j)
+			if !skipComponents.Get(id) {
+				used.Set(id, true)
+			}
+		}
+	}
+	return used
+}
+
+// marshalLocal marshals a component to JSON, with all entities replaced by local entities.
+func marshalLocal(tp reflect.Type, ptr unsafe.Pointer, local *entityRemap, opts *serdeOptions) ([]byte, error) {
+	if !local.types.contains(tp) {
+		return encodeValue(tp, ptr, opts)
+	}
+
+	// Entities are replaced in a deep copy, to leave the component unchanged.
+	jsonData, err := encodeValue(tp, ptr, opts)
+	if err != nil {
+		return nil, err
+	}
+	copied := reflect.New(tp)
+	if err := decodeValue(tp, jsonData, copied.Interface(), opts); err != nil {
+		return nil, err
+	}
+	local.value(copied.Elem())
+	return encodeValue(tp, copied.UnsafePointer(), opts)
+}
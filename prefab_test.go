@@ -0,0 +1,161 @@
+package arkserde_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeEntities(t *testing.T) {
+	w := createWorld(false)
+	posMap := ecs.NewMap1[Position](w)
+
+	// Unrelated entities.
+	for i := range 5 {
+		posMap.NewEntity(&Position{X: float64(100 + i)})
+	}
+
+	root := posMap.NewEntity(&Position{X: 1})
+	child := ecs.NewMap2[Position, ChildRelation](w).NewEntity(&Position{X: 2}, &ChildRelation{Dummy: 1}, ecs.RelIdx(1, root))
+	grandChild := ecs.NewMap2[Position, ChildOf](w).NewEntity(&Position{X: 3}, &ChildOf{Entity: child})
+	dead := posMap.NewEntity(&Position{})
+	w.RemoveEntity(dead)
+	other := posMap.NewEntity(&Position{X: 4})
+	targets := ecs.NewMap1[Targets](w).NewEntity(&Targets{
+		Entities: []ecs.Entity{grandChild, dead},
+		ByName:   map[string]ecs.Entity{"other": other},
+		Next:     &root,
+	})
+
+	data, err := arkserde.SerializeEntities(w, []ecs.Entity{targets, root})
+	assert.Nil(t, err)
+	fmt.Println(string(data))
+
+	// The world is not modified.
+	tg := ecs.NewMap[Targets](w).Get(targets)
+	assert.Equal(t, root, *tg.Next)
+
+	w2 := newWorldLike(w)
+	ecs.NewMap1[Position](w2).NewBatchFn(10, nil)
+
+	for n := range 3 {
+		roots, err := arkserde.Instantiate(data, w2)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, len(roots))
+		assert.Equal(t, 10+(n+1)*5, countAll(w2))
+
+		tg := ecs.NewMap[Targets](w2).Get(roots[0])
+		assert.Equal(t, roots[1], *tg.Next)
+		assert.Equal(t, ecs.Entity{}, tg.Entities[1])
+
+		posMap := ecs.NewMap[Position](w2)
+		assert.Equal(t, Position{X: 1}, *posMap.Get(roots[1]))
+		assert.Equal(t, Position{X: 4}, *posMap.Get(tg.ByName["other"]))
+
+		grandChild := tg.Entities[0]
+		assert.Equal(t, Position{X: 3}, *posMap.Get(grandChild))
+		child := ecs.NewMap[ChildOf](w2).Get(grandChild).Entity
+		assert.Equal(t, Position{X: 2}, *posMap.Get(child))
+		assert.Equal(t, roots[1], ecs.NewMap[ChildRelation](w2).GetRelation(child))
+	}
+}
+
+func TestSerializeEntitiesOptions(t *testing.T) {
+	w := createWorld(false)
+	root := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	child := ecs.NewMap2[Position, ChildOf](w).NewEntity(&Position{X: 2}, &ChildOf{Entity: root})
+
+	data, err := arkserde.SerializeEntities(w, []ecs.Entity{child},
+		arkserde.Opts.Compress(),
+		arkserde.Opts.SkipComponents(ecs.C[ChildOf]()),
+	)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	roots, err := arkserde.Instantiate(data, w2, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countAll(w2))
+	assert.Equal(t, 0, countEntities[ChildOf](w2))
+	assert.Equal(t, 1, len(roots))
+
+	data, err = arkserde.SerializeEntities(w, []ecs.Entity{root, root})
+	assert.Nil(t, err)
+
+	w2 = newWorldLike(w)
+	roots, err = arkserde.Instantiate(data, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countAll(w2))
+	assert.Equal(t, []ecs.Entity{roots[0], roots[0]}, roots)
+}
+
+func TestSerializeEntitiesTypes(t *testing.T) {
+	w := createWorld(true)
+	root := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap2[Position, Velocity](w).NewEntity(&Position{X: 2}, &Velocity{X: 3})
+
+	data, err := arkserde.SerializeEntities(w, []ecs.Entity{root})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "Velocity")
+
+	// Types of other entities are not required.
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	roots, err := arkserde.Instantiate(data, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(roots))
+	assert.Equal(t, 1, countAll(w2))
+}
+
+func TestSerializeEntitiesConcurrent(t *testing.T) {
+	w := createWorld(false)
+	root := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	child := ecs.NewMap2[Position, ChildRelation](w).NewEntity(&Position{X: 2}, &ChildRelation{}, ecs.RelIdx(1, root))
+
+	// Serializing does not write to the world, so it can run concurrently with readers.
+	wg := sync.WaitGroup{}
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := arkserde.SerializeEntities(w, []ecs.Entity{child})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSerializeEntitiesErrors(t *testing.T) {
+	w := createWorld(false)
+	root := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+
+	dead := w.NewEntity()
+	w.RemoveEntity(dead)
+	_, err := arkserde.SerializeEntities(w, []ecs.Entity{root, dead})
+	assert.EqualError(t, err, fmt.Sprintf("root entity %v is not alive", dead))
+
+	data, err := arkserde.SerializeEntities(w, []ecs.Entity{root})
+	assert.Nil(t, err)
+
+	_, err = arkserde.Instantiate(data, ecs.NewWorld(1024))
+	assert.EqualError(t, err, "Types: arkserde_test.Position: component type is not registered")
+
+	w2 := newWorldLike(w)
+	_, err = arkserde.Instantiate([]byte(textPrefabErrRoot), w2)
+	assert.EqualError(t, err, "Roots: root index 1 out of range for 1 entities")
+	assert.Equal(t, 0, countAll(w2))
+}
+
+const textPrefabErrRoot = `{
+"Version" : {"Format":1,"Schema":0},
+"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+"Types" : ["arkserde_test.Position"],
+"Components" : [
+  {"arkserde_test.Position" : {"X":1,"Y":0}}
+],
+"Roots" : [1],
+"Resources" : {}
+}`
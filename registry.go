@@ -0,0 +1,136 @@
+package arkserde
+
+import (
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// registry is the global registry of component and resource types.
+var registry = typeRegistry{}
+
+// Register adds a component type to the global registry.
+//
+// When deserializing, component types found in the data that are not registered in the target world,
+// but in the registry, are registered in the world automatically.
+// Types are identified by the same names as in serialized data,
+// so they are also found under names set with [Options.QualifiedNames] and [Options.Alias].
+//
+// Registering a type multiple times has no effect. Register is safe for concurrent use.
+func Register[T any]() {
+	registry.add(&registry.components, reflect.TypeFor[T]())
+}
+
+// RegisterResource adds a resource type to the global registry.
+//
+// When deserializing, resources found in the data that are not present in the target world,
+// but in the registry, are added to the world automatically, with a zero value.
+// See [Register] for details.
+func RegisterResource[T any]() {
+	registry.add(&registry.resources, reflect.TypeFor[T]())
+}
+
+type typeRegistry struct {
+	mu         sync.RWMutex
+	components []reflect.Type
+	resources  []reflect.Type
+}
+
+func (r *typeRegistry) add(types *[]reflect.Type, tp reflect.Type) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !slices.Contains(*types, tp) {
+		*types = append(*types, tp)
+	}
+}
+
+// byName returns the registered component or resource types by their names, including aliases.
+// Returns an error if two registered types have the same name.
+func (r *typeRegistry) byName(kind string, opts *serdeOptions) (map[string]reflect.Type, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := r.components
+	if kind == "resource" {
+		types = r.resources
+	}
+
+	uniqueNames := typeNames{}
+	for _, tp := range types {
+		if err := uniqueNames.add(typeName(tp, opts), tp, kind); err != nil {
+			return nil, err
+		}
+	}
+	for _, alias := range opts.aliases {
+		if !slices.Contains(types, alias.tp) {
+			continue
+		}
+		if err := uniqueNames.add(alias.name, alias.tp, kind); err != nil {
+			return nil, err
+		}
+	}
+	return uniqueNames, nil
+}
+
+// registerComponents registers component types with the given names in the world,
+// if they are in the global registry, but not yet registered in the world.
+func registerComponents(world *ecs.World, names []string, opts *serdeOptions) error {
+	registered, err := registry.byName("component", opts)
+	if err != nil || len(registered) == 0 {
+		return err
+	}
+
+	known := map[string]bool{}
+	for _, name := range componentNames(world, opts) {
+		known[name] = true
+	}
+
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		if tp, ok := registered[name]; ok {
+			_ = ecs.TypeID(world, tp)
+			known[typeName(tp, opts)] = true
+		}
+	}
+	return nil
+}
+
+// registerResources adds resources with the given names to the world,
+// if they are in the global registry, but not yet present in the world.
+// Returns the IDs of the added resources.
+func registerResources(world *ecs.World, names []string, opts *serdeOptions) ([]ecs.ResID, error) {
+	registered, err := registry.byName("resource", opts)
+	if err != nil || len(registered) == 0 {
+		return nil, err
+	}
+
+	// Resources that are present, or registered under the same name as a different type.
+	known := map[string]bool{}
+	for _, id := range ecs.ResourceIDs(world) {
+		if tp, ok := ecs.ResourceType(world, id); ok {
+			name := typeName(tp, opts)
+			known[name] = world.Resources().Get(id) != nil || registered[name] != tp
+		}
+	}
+
+	added := []ecs.ResID{}
+	for _, name := range names {
+		if known[name] {
+			continue
+		}
+		if tp, ok := registered[name]; ok {
+			id := ecs.ResourceTypeID(world, tp)
+			if world.Resources().Get(id) == nil {
+				world.Resources().Add(id, reflect.New(tp).Interface())
+				added = append(added, id)
+			}
+			known[typeName(tp, opts)] = true
+		}
+	}
+	return added, nil
+}
@@ -0,0 +1,128 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+// Types for the global registry, not used by other tests.
+type (
+	RegPosition struct{ X, Y float64 }
+	RegParent   struct {
+		ecs.RelationMarker
+	}
+	RegConfig  struct{ Steps int }
+	RegUnknown struct{}
+)
+
+func init() {
+	arkserde.Register[RegPosition]()
+	arkserde.Register[RegPosition]()
+	arkserde.Register[RegParent]()
+	arkserde.RegisterResource[RegConfig]()
+}
+
+func TestRegister(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	parent := ecs.NewMap1[RegPosition](w).NewEntity(&RegPosition{X: 1})
+	ecs.NewMap2[RegPosition, RegParent](w).NewEntity(&RegPosition{X: 2}, &RegParent{}, ecs.RelIdx(1, parent))
+	ecs.AddResource(w, &RegConfig{Steps: 10})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+		{arkserde.Opts.QualifiedNames()},
+	} {
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := ecs.NewWorld(1024)
+		err = arkserde.Deserialize(jsonData, w2, opts...)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, w, w2)
+		assert.Equal(t, RegConfig{Steps: 10}, *ecs.GetResource[RegConfig](w2))
+
+		w2 = ecs.NewWorld(1024)
+		err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w2, opts...)
+		assert.Nil(t, err)
+		assertEqualWorlds(t, w, w2)
+		assert.Equal(t, RegConfig{Steps: 10}, *ecs.GetResource[RegConfig](w2))
+	}
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+	w2 := ecs.NewWorld(1024)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+	assertEqualWorlds(t, w, w2)
+	assert.Equal(t, RegConfig{Steps: 10}, *ecs.GetResource[RegConfig](w2))
+}
+
+func TestRegisterExisting(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	ecs.NewMap2[RegPosition, RegParent](w).NewEntity(&RegPosition{X: 1}, &RegParent{}, ecs.RelIdx(1, ecs.Entity{}))
+	ecs.AddResource(w, &RegConfig{Steps: 10})
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	// Resource is registered, but not added.
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ResourceID[RegConfig](w2)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, RegConfig{Steps: 10}, *ecs.GetResource[RegConfig](w2))
+
+	// Resource is present.
+	w2 = ecs.NewWorld(1024)
+	_ = ecs.ComponentID[RegParent](w2)
+	res := RegConfig{}
+	ecs.AddResource(w2, &res)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, RegConfig{Steps: 10}, res)
+}
+
+func TestRegisterAlias(t *testing.T) {
+	jsonData := []byte(textRegisterAlias)
+
+	w := ecs.NewWorld(1024)
+	err := arkserde.Deserialize(jsonData, w,
+		arkserde.Opts.Alias("old.Position", ecs.C[RegPosition]()),
+		arkserde.Opts.Alias("old.Config", ecs.C[RegConfig]()),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countEntities[RegPosition](w))
+	assert.Equal(t, RegConfig{Steps: 5}, *ecs.GetResource[RegConfig](w))
+
+	w = ecs.NewWorld(1024)
+	err = arkserde.Deserialize(jsonData, w)
+	assert.EqualError(t, err, "Types: old.Position: component type is not registered")
+}
+
+func TestRegisterErrors(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	ecs.NewMap1[RegUnknown](w).NewEntity(&RegUnknown{})
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w2 := ecs.NewWorld(1024)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.EqualError(t, err, "Types: arkserde_test.RegUnknown: component type is not registered")
+}
+
+const textRegisterAlias = `{
+"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+"Types" : ["old.Position"],
+"Components" : [
+  {"old.Position" : {"X":1,"Y":2}}
+],
+"Resources" : {
+  "old.Config" : {"Steps":5}
+}
+}`
@@ -0,0 +1,87 @@
+package arkserde
+
+import (
+	"encoding/binary"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// entitySelection is the set of entities selected for serialization,
+// see [Options.With], [Options.Without] and [Options.OnlyEntities].
+// A nil selection contains all entities.
+type entitySelection struct {
+	selected []bool // Selected entities, by entity ID.
+}
+
+// contains checks whether an alive entity is selected.
+func (s *entitySelection) contains(entity ecs.Entity) bool {
+	return s == nil || s.selected[entity.ID()]
+}
+
+// target returns the relation target to serialize.
+// Targets that are not selected are replaced by the zero entity,
+// like when removing a target entity from a world.
+func (s *entitySelection) target(entity ecs.Entity) ecs.Entity {
+	if entity.IsZero() || s.contains(entity) {
+		return entity
+	}
+	return ecs.Entity{}
+}
+
+// selectEntities returns the entity dump to serialize, and the selected entities.
+//
+// Entities that are not selected are written to the dump as if they were removed from the world,
+// so that partial data can be deserialized like any other data.
+func selectEntities(world *ecs.World, opts *serdeOptions) (ecs.EntityDump, *entitySelection) {
+	dump := world.Unsafe().DumpEntities()
+	if !opts.hasFilter() {
+		return dump, nil
+	}
+
+	with := make([]ecs.ID, len(opts.with))
+	for i, tp := range opts.with {
+		with[i] = ecs.TypeID(world, tp)
+	}
+	without := make([]ecs.ID, len(opts.without))
+	for i, tp := range opts.without {
+		without[i] = ecs.TypeID(world, tp)
+	}
+
+	selection := entitySelection{selected: make([]bool, len(dump.Entities))}
+	query := ecs.NewUnsafeFilter(world, with...).Without(without...).Query()
+	for query.Next() {
+		entity := query.Entity()
+		if opts.onlyEntities != nil {
+			if _, ok := opts.onlyEntities[entity]; !ok {
+				continue
+			}
+		}
+		selection.selected[entity.ID()] = true
+	}
+
+	alive := make([]uint32, 0, len(dump.Alive))
+	for _, id := range dump.Alive {
+		if selection.selected[id] {
+			alive = append(alive, id)
+			continue
+		}
+		// Recycle the entity, like the world's entity pool does when removing it.
+		entity := dump.Entities[id]
+		dump.Entities[id] = newEntity(dump.Next, entity.Gen()+1)
+		dump.Next = id
+		dump.Available++
+	}
+	dump.Alive = alive
+
+	return dump, &selection
+}
+
+// newEntity creates an entity with the given ID and generation.
+func newEntity(id, gen uint32) ecs.Entity {
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], id)
+	binary.BigEndian.PutUint32(buf[4:], gen)
+	entity := ecs.Entity{}
+	_ = entity.UnmarshalBinary(buf[:])
+	return entity
+}
@@ -0,0 +1,146 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeWith(t *testing.T) {
+	w := createArchetypesWorld()
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		jsonData, err := arkserde.Serialize(w, append(opts, arkserde.Opts.With(ecs.C[Velocity]()))...)
+		assert.Nil(t, err)
+
+		w2 := createWorld(true)
+		err = arkserde.Deserialize(jsonData, w2)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 10, countAll(w2))
+		assert.Equal(t, 10, countEntities[Velocity](w2))
+
+		query := ecs.NewFilter2[Position, Velocity](w).Query()
+		posMap := ecs.NewMap2[Position, Velocity](w2)
+		for query.Next() {
+			entity := query.Entity()
+			assert.True(t, w2.Alive(entity))
+			pos, vel := posMap.Get(entity)
+			expPos, expVel := query.Get()
+			assert.Equal(t, *expPos, *pos)
+			assert.Equal(t, *expVel, *vel)
+		}
+
+		// Entities that were not serialized are recycled.
+		velMap := ecs.NewMap[Velocity](w2)
+		for range 50 {
+			e := w2.NewEntity()
+			assert.False(t, velMap.Has(e))
+		}
+		assert.Equal(t, 60, countAll(w2))
+		assert.Equal(t, 10, countEntities[Velocity](w2))
+	}
+}
+
+func TestSerializeWithout(t *testing.T) {
+	w := createArchetypesWorld()
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.Without(ecs.C[ChildRelation](), ecs.C[Velocity]()))
+	assert.Nil(t, err)
+
+	w2 := createWorld(true)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 7, countAll(w2))
+	assert.Equal(t, 2, countEntities[Position](w2))
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+	assert.Equal(t, 0, countEntities[ChildRelation](w2))
+}
+
+func TestSerializeOnlyEntities(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	posMap := ecs.NewMap1[Position](w)
+	relMap := ecs.NewMap2[Position, ChildRelation](w)
+	childMap := ecs.NewMap2[Position, ChildOf](w)
+
+	parent1 := posMap.NewEntity(&Position{X: 1})
+	parent2 := posMap.NewEntity(&Position{X: 2})
+	child1 := relMap.NewEntity(&Position{X: 3}, &ChildRelation{Dummy: 1}, ecs.RelIdx(1, parent1))
+	child2 := relMap.NewEntity(&Position{X: 4}, &ChildRelation{Dummy: 2}, ecs.RelIdx(1, parent2))
+	child3 := childMap.NewEntity(&Position{X: 5}, &ChildOf{Entity: parent2})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		opts = append(opts, arkserde.Opts.OnlyEntities(parent1, child1, child2, child3))
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Position](w2)
+		_ = ecs.ComponentID[ChildRelation](w2)
+		_ = ecs.ComponentID[ChildOf](w2)
+
+		err = arkserde.DeserializeFrom(bytes.NewReader(jsonData), w2)
+		assert.Nil(t, err)
+
+		assert.Equal(t, 4, countAll(w2))
+		assert.False(t, w2.Alive(parent2))
+
+		relMap2 := ecs.NewMap[ChildRelation](w2)
+		assert.Equal(t, parent1, relMap2.GetRelation(child1))
+		assert.Equal(t, ecs.Entity{}, relMap2.GetRelation(child2))
+		// Entities in components are kept.
+		assert.Equal(t, ChildOf{Entity: parent2}, *ecs.NewMap[ChildOf](w2).Get(child3))
+	}
+
+	jsonData, err := arkserde.Serialize(w,
+		arkserde.Opts.OnlyEntities(parent1, child1, child2),
+		arkserde.Opts.With(ecs.C[ChildRelation]()),
+	)
+	assert.Nil(t, err)
+
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	_ = ecs.ComponentID[ChildRelation](w2)
+	_ = ecs.ComponentID[ChildOf](w2)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, countAll(w2))
+	assert.True(t, w2.Alive(child1))
+	assert.True(t, w2.Alive(child2))
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.OnlyEntities())
+	assert.Nil(t, err)
+
+	w2 = ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+	_ = ecs.ComponentID[ChildRelation](w2)
+	_ = ecs.ComponentID[ChildOf](w2)
+	err = arkserde.Deserialize(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countAll(w2))
+}
+
+func TestSerializeBinaryWith(t *testing.T) {
+	w := createArchetypesWorld()
+
+	data, err := arkserde.SerializeBinary(w, arkserde.Opts.With(ecs.C[ChildRelation]()))
+	assert.Nil(t, err)
+
+	w2 := createWorld(true)
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+
+	assert.Equal(t, 14, countAll(w2))
+	assert.Equal(t, 14, countEntities[ChildRelation](w2))
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+}
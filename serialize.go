@@ -1,9 +1,11 @@
 package arkserde
 
 import (
+	"bufio"
+	"bytes"
+	"io"
 	"reflect"
-	"slices"
-	"strings"
+	"strconv"
 
 	"github.com/goccy/go-json"
 	"github.com/mlange-42/ark/ecs"
@@ -17,226 +19,379 @@ import (
 //   - All resources
 //
 // All components and resources must be "JSON-able" with [encoding/json].
+// Components and resources can prepare their data by implementing [BeforeSerializer].
+//
+// The output is deterministic: component types and resources are written in the order of their IDs,
+// so serializing the same world repeatedly results in identical bytes.
 //
 // The options can be used to skip some or all components,
 // entities entirely, and/or some or all resources.
 func Serialize(world *ecs.World, options ...Option) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if err := SerializeTo(&buffer, world, options...); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// SerializeTo serializes an Ark [ecs.World] to JSON, and writes it to the given [io.Writer].
+//
+// In contrast to [Serialize], the JSON document is streamed to the writer
+// and never held in memory as a whole.
+// Use it to write large worlds directly to a file or a network connection.
+// With option [Options.Compress], the output is compressed on the fly.
+//
+// See [Serialize] for details on what is serialized.
+func SerializeTo(w io.Writer, world *ecs.World, options ...Option) error {
 	opts := newSerdeOptions(options...)
 
-	builder := strings.Builder{}
+	if opts.compressed {
+		return writeGZip(w, opts.compressionLevel, func(w io.Writer) error {
+			return serializeTo(w, world, &opts)
+		})
+	}
+	return serializeTo(w, world, &opts)
+}
+
+func serializeTo(w io.Writer, world *ecs.World, opts *serdeOptions) error {
+	writer := bufio.NewWriterSize(w, writeBufferSize)
 
-	builder.WriteString("{\n")
+	entities, selection := selectEntities(world, opts)
+	unknown := newUnknownComponents(world, componentNames(world, opts), opts, selection)
 
-	if err := serializeWorld(world, &builder, &opts); err != nil {
-		return nil, err
+	writer.WriteString("{\n")
+
+	serializeVersion(writer, opts)
+	writer.WriteString(",\n")
+
+	if err := serializeWorld(&entities, writer, opts); err != nil {
+		return err
 	}
 	if !opts.skipEntities {
-		builder.WriteString(",\n")
+		writer.WriteString(",\n")
 	}
 
-	serializeTypes(world, &builder, &opts)
-	builder.WriteString(",\n")
-
-	if err := serializeComponents(world, &builder, &opts); err != nil {
-		return nil, err
+	if err := serializeTypes(world, writer, opts, nil, unknown); err != nil {
+		return err
 	}
-	builder.WriteString(",\n")
+	writer.WriteString(",\n")
 
-	if err := serializeResources(world, &builder, &opts); err != nil {
-		return nil, err
+	if opts.columnar {
+		if err := serializeArchetypes(world, writer, opts, selection, unknown); err != nil {
+			return err
+		}
+	} else {
+		if err := serializeComponents(world, writer, opts, selection, unknown, len(entities.Alive)); err != nil {
+			return err
+		}
 	}
-	builder.WriteString("}\n")
+	writer.WriteString(",\n")
 
-	data := []byte(builder.String())
-	if opts.compressed {
-		var err error
-		data, err = compressGZip(data, opts.compressionLevel)
-		if err != nil {
-			return nil, err
-		}
+	if err := serializeResources(world, writer, opts); err != nil {
+		return err
 	}
+	writer.WriteString("}\n")
 
-	return data, nil
+	return writer.Flush()
 }
 
-func serializeWorld(world *ecs.World, builder *strings.Builder, opts *serdeOptions) error {
+func serializeVersion(writer *bufio.Writer, opts *serdeOptions) {
+	writer.WriteString("\"Version\" : {\"Format\":")
+	writer.WriteString(strconv.Itoa(FormatVersion))
+	writer.WriteString(",\"Schema\":")
+	writer.WriteString(strconv.Itoa(opts.schemaVersion))
+	writer.WriteString("}")
+}
+
+func serializeWorld(entities *ecs.EntityDump, writer *bufio.Writer, opts *serdeOptions) error {
 	if opts.skipEntities {
 		return nil
 	}
 
-	entities := world.Unsafe().DumpEntities()
-
 	jsonData, err := json.Marshal(entities)
 	if err != nil {
 		return err
 	}
-	builder.WriteString("\"World\" : ")
-	builder.Write(jsonData)
+	writer.WriteString("\"World\" : ")
+	writer.Write(jsonData)
 	return nil
 }
 
-func serializeTypes(world *ecs.World, builder *strings.Builder, opts *serdeOptions) {
+// serializeTypes writes the names of all component types that are not skipped.
+// If used is not nil, only types in used are written.
+func serializeTypes(world *ecs.World, writer *bufio.Writer, opts *serdeOptions, used *bitMask, unknown *unknownComponents) error {
 	if opts.skipEntities || opts.skipAllComponents {
-		builder.WriteString("\"Types\" : []")
-		return
+		writer.WriteString("\"Types\" : []")
+		return nil
 	}
 
-	builder.WriteString("\"Types\" : [\n")
+	writer.WriteString("\"Types\" : [\n")
 
-	types := map[ecs.ID]reflect.Type{}
+	// Types are collected in the order of their IDs,
+	// so that the output is the same for repeated serialization.
+	names := []string{}
+	uniqueNames := typeNames{}
 
 	allComps := ecs.ComponentIDs(world)
 	for _, id := range allComps {
 		if info, ok := ecs.ComponentInfo(world, id); ok {
-			if !slices.Contains(opts.skipComponents, info.Type) {
-				types[id] = info.Type
+			if used != nil && !used.Get(id) {
+				continue
+			}
+			if !opts.skipsComponent(info.Type) {
+				name := typeName(info.Type, opts)
+				if err := uniqueNames.add(name, info.Type, "component"); err != nil {
+					return err
+				}
+				names = append(names, name)
 			}
 		}
 	}
-	maxComp := len(types) - 1
-	counter := 0
-	for _, tp := range types {
-		builder.WriteString("  \"")
-		builder.WriteString(tp.String())
-		builder.WriteByte('"')
-		if counter < maxComp {
-			builder.WriteString(",")
-		}
-		builder.WriteString("\n")
-		counter++
+	names = append(names, unknown.types()...)
+	maxComp := len(names) - 1
+	for i, name := range names {
+		writer.WriteString("  \"")
+		writer.WriteString(name)
+		writer.WriteByte('"')
+		if i < maxComp {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
 	}
 
-	builder.WriteString("]")
+	writer.WriteString("]")
+	return nil
 }
 
-func serializeComponents(world *ecs.World, builder *strings.Builder, opts *serdeOptions) error {
+func serializeComponents(world *ecs.World, writer *bufio.Writer, opts *serdeOptions, selection *entitySelection, unknown *unknownComponents, count int) error {
 	if opts.skipEntities {
-		builder.WriteString("\"Components\" : []")
+		writer.WriteString("\"Components\" : []")
 		return nil
 	}
 
-	skipComponents := bitMask{}
-	for _, tp := range opts.skipComponents {
-		id := ecs.TypeID(world, tp)
-		skipComponents.Set(id, true)
+	enc := entityEncoder{
+		world:          world,
+		opts:           opts,
+		selection:      selection,
+		unknown:        unknown,
+		names:          componentNames(world, opts),
+		infos:          componentInfos(world),
+		skipComponents: skipMask(world, opts),
 	}
+	lastEntity := count - 1
 
-	builder.WriteString("\"Components\" : [\n")
+	writer.WriteString("\"Components\" : [\n")
 
-	query := ecs.NewUnsafeFilter(world).Query()
-	lastEntity := query.Count() - 1
-	counter := 0
-	tempIDs := []ecs.ID{}
-	for query.Next() {
-		if opts.skipAllComponents {
-			builder.WriteString("  {")
-		} else {
-			builder.WriteString("  {\n")
-
-			ids := query.IDs()
-
-			tempIDs = tempIDs[:0]
-			for i := range ids.Len() {
-				id := ids.Get(i)
-				if !skipComponents.Get(id) {
-					tempIDs = append(tempIDs, id)
-				}
+	if opts.parallel > 1 {
+		entities := make([]ecs.Entity, 0, count)
+		query := ecs.NewUnsafeFilter(world).Query()
+		for query.Next() {
+			if selection.contains(query.Entity()) {
+				entities = append(entities, query.Entity())
 			}
-			last := len(tempIDs) - 1
-
-			for i, id := range tempIDs {
-				info, _ := ecs.ComponentInfo(world, id)
-
-				if info.IsRelation {
-					target := query.GetRelation(id)
-					eJSON, err := target.MarshalJSON()
-					if err != nil {
-						return err
-					}
-					// the following replaces an expensive fmt.Fprintf call;
-					// it is equivalent to the following:
-					//fmt.Fprintf(builder, "    \"%s%s\" : %s,\n", info.Type.String(), targetTag, eJSON)
-					builder.WriteString("    \"")
-					builder.WriteString(info.Type.String())
-					builder.WriteString(targetTag)
-					builder.WriteString("\" : ")
-					builder.Write(eJSON)
-					builder.WriteString(",\n")
-				}
+		}
 
-				comp := query.Get(id)
-				value := reflect.NewAt(info.Type, comp).Interface()
-				jsonData, err := json.Marshal(value)
-				if err != nil {
+		buffers := make([]bytes.Buffer, opts.parallel)
+		err := runChunks(len(entities), opts.parallel, func(slot, start, end int) error {
+			buffers[slot].Reset()
+			buffer := bufio.NewWriter(&buffers[slot])
+			tempIDs := []ecs.ID{}
+			for i := start; i < end; i++ {
+				if err := enc.encode(buffer, entities[i], &tempIDs); err != nil {
 					return err
 				}
-				builder.WriteString("    \"")
-				builder.WriteString(info.Type.String())
-				builder.WriteString("\" : ")
-				builder.Write(jsonData)
-				if i < last {
-					builder.WriteString(",")
+				if i < lastEntity {
+					buffer.WriteString(",")
 				}
-				builder.WriteString("\n")
+				buffer.WriteString("\n")
 			}
+			return buffer.Flush()
+		}, func(slot int) error {
+			_, err := writer.Write(buffers[slot].Bytes())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		writer.WriteString("]")
+		return nil
+	}
+
+	query := ecs.NewUnsafeFilter(world).Query()
+	counter := 0
+	tempIDs := []ecs.ID{}
+	for query.Next() {
+		if !selection.contains(query.Entity()) {
+			continue
+		}
+		if err := enc.encode(writer, query.Entity(), &tempIDs); err != nil {
+			query.Close()
+			return err
 		}
-		builder.WriteString("  }")
 		if counter < lastEntity {
-			builder.WriteString(",")
+			writer.WriteString(",")
 		}
-		builder.WriteString("\n")
+		writer.WriteString("\n")
 
 		counter++
 	}
-	builder.WriteString("]")
+	writer.WriteString("]")
 
 	return nil
 }
 
-func serializeResources(world *ecs.World, builder *strings.Builder, opts *serdeOptions) error {
+// entityEncoder writes the components of entities in the default layout.
+// It can be used concurrently, as long as the world is not modified.
+type entityEncoder struct {
+	world          *ecs.World
+	opts           *serdeOptions
+	selection      *entitySelection
+	unknown        *unknownComponents
+	names          []string
+	infos          []ecs.CompInfo
+	skipComponents bitMask
+}
+
+// encode writes the components of an entity, as a JSON object.
+// The slice tempIDs is re-used between calls.
+func (e *entityEncoder) encode(writer *bufio.Writer, entity ecs.Entity, tempIDs *[]ecs.ID) error {
+	if e.opts.skipAllComponents {
+		writer.WriteString("  {  }")
+		return nil
+	}
+	writer.WriteString("  {\n")
+
+	u := e.world.Unsafe()
+	ids := u.IDs(entity)
+
+	*tempIDs = (*tempIDs)[:0]
+	for i := range ids.Len() {
+		id := ids.Get(i)
+		if !e.skipComponents.Get(id) {
+			*tempIDs = append(*tempIDs, id)
+		}
+	}
+	unknownKeys := e.unknown.keys(entity)
+	last := len(*tempIDs) + len(unknownKeys) - 1
+
+	for i, id := range *tempIDs {
+		info := e.infos[id.Index()]
+
+		if info.IsRelation {
+			target := e.selection.target(u.GetRelation(entity, id))
+			eJSON, err := target.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			// the following replaces an expensive fmt.Fprintf call;
+			// it is equivalent to the following:
+			//fmt.Fprintf(writer, "    \"%s%s\" : %s,\n", names[id.Index()], targetTag, eJSON)
+			writer.WriteString("    \"")
+			writer.WriteString(e.names[id.Index()])
+			writer.WriteString(targetTag)
+			writer.WriteString("\" : ")
+			writer.Write(eJSON)
+			writer.WriteString(",\n")
+		}
+
+		jsonData, err := encodeValue(info.Type, u.Get(entity, id), e.opts)
+		if err != nil {
+			return err
+		}
+		writer.WriteString("    \"")
+		writer.WriteString(e.names[id.Index()])
+		writer.WriteString("\" : ")
+		writer.Write(jsonData)
+		if i < last {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
+	}
+
+	for i, key := range unknownKeys {
+		jsonData, err := e.unknown.value(entity, key)
+		if err != nil {
+			return err
+		}
+		writer.WriteString("    \"")
+		writer.WriteString(key)
+		writer.WriteString("\" : ")
+		writer.Write(jsonData)
+		if len(*tempIDs)+i < last {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
+	}
+	writer.WriteString("  }")
+	return nil
+}
+
+func serializeResources(world *ecs.World, writer *bufio.Writer, opts *serdeOptions) error {
 	if opts.skipAllResources {
-		builder.WriteString("\"Resources\" : {}")
+		writer.WriteString("\"Resources\" : {}")
 		return nil
 	}
 
-	builder.WriteString("\"Resources\" : {\n")
+	writer.WriteString("\"Resources\" : {\n")
 
-	resTypes := map[ecs.ResID]reflect.Type{}
+	// Resources are collected in the order of their IDs,
+	// so that the output is the same for repeated serialization.
+	resIDs := []ecs.ResID{}
+	resTypes := []reflect.Type{}
+	uniqueNames := typeNames{}
 	allRes := ecs.ResourceIDs(world)
 	for _, id := range allRes {
-		if tp, ok := ecs.ResourceType(world, id); ok {
-			if !slices.Contains(opts.skipResources, tp) {
-				resTypes[id] = tp
+		if tp, ok := ecs.ResourceType(world, id); ok && tp != unknownDataType {
+			if !opts.skipsResource(tp) {
+				if err := uniqueNames.add(typeName(tp, opts), tp, "resource"); err != nil {
+					return err
+				}
+				resIDs = append(resIDs, id)
+				resTypes = append(resTypes, tp)
 			}
 		}
 	}
 
-	last := len(resTypes) - 1
-	counter := 0
-	for id, tp := range resTypes {
+	// Preserved resources of unknown types are written after registered ones, sorted by name.
+	unknown := unknownResources(world, uniqueNames, opts)
+	unknownNames := sortedKeys(unknown)
+
+	last := len(resTypes) + len(unknownNames) - 1
+	for i, id := range resIDs {
+		tp := resTypes[i]
 		res := world.Resources().Get(id)
 		rValue := reflect.ValueOf(res)
 		ptr := rValue.UnsafePointer()
 
-		value := reflect.NewAt(tp, ptr).Interface()
-		jsonData, err := json.Marshal(value)
+		jsonData, err := encodeValue(tp, ptr, opts)
 		if err != nil {
 			return err
 		}
 
-		builder.WriteString("    \"")
-		builder.WriteString(tp.String())
-		builder.WriteString("\" : ")
-		builder.Write(jsonData)
+		writer.WriteString("    \"")
+		writer.WriteString(typeName(tp, opts))
+		writer.WriteString("\" : ")
+		writer.Write(jsonData)
 
-		if counter < last {
-			builder.WriteString(",")
+		if i < last {
+			writer.WriteString(",")
 		}
-		builder.WriteString("\n")
-		counter++
+		writer.WriteString("\n")
+	}
+
+	for i, name := range unknownNames {
+		writer.WriteString("    \"")
+		writer.WriteString(name)
+		writer.WriteString("\" : ")
+		writer.Write(unknown[name])
+
+		if len(resTypes)+i < last {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
 	}
 
-	builder.WriteString("}")
+	writer.WriteString("}")
 
 	return nil
 }
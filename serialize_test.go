@@ -1,10 +1,15 @@
 package arkserde_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"reflect"
 	"testing"
 
 	arkserde "github.com/mlange-42/ark-serde"
+	modelOne "github.com/mlange-42/ark-serde/internal/test/one/model"
+	modelTwo "github.com/mlange-42/ark-serde/internal/test/two/model"
 	"github.com/mlange-42/ark/ecs"
 	"github.com/stretchr/testify/assert"
 )
@@ -59,6 +64,42 @@ func serialize(opts ...arkserde.Option) ([]byte, ecs.Entity, ecs.Entity, error)
 	return js, parent, child, err
 }
 
+// assertEqualWorlds checks that two worlds have the same alive entities,
+// with equal components and relation targets.
+// Component types must be registered in the same order in both worlds.
+func assertEqualWorlds(t *testing.T, expected, actual *ecs.World) {
+	t.Helper()
+
+	u1 := expected.Unsafe()
+	u2 := actual.Unsafe()
+
+	assert.Equal(t, countAll(expected), countAll(actual))
+	query := ecs.NewUnsafeFilter(expected).Query()
+	for query.Next() {
+		entity := query.Entity()
+		if !assert.True(t, actual.Alive(entity), "entity %v is not alive", entity) {
+			continue
+		}
+		ids1, ids2 := u1.IDs(entity), u2.IDs(entity)
+		if !assert.Equal(t, ids1.Len(), ids2.Len(), "entity %v has different components", entity) {
+			continue
+		}
+		for i := range ids1.Len() {
+			id := ids1.Get(i)
+			info, _ := ecs.ComponentInfo(expected, id)
+			if !assert.True(t, u2.Has(entity, id), "entity %v is missing %s", entity, info.Type) {
+				continue
+			}
+			v1 := reflect.NewAt(info.Type, u1.Get(entity, id)).Elem().Interface()
+			v2 := reflect.NewAt(info.Type, u2.Get(entity, id)).Elem().Interface()
+			assert.Equal(t, v1, v2)
+			if info.IsRelation {
+				assert.Equal(t, u1.GetRelation(entity, id), u2.GetRelation(entity, id))
+			}
+		}
+	}
+}
+
 func TestSerialize(t *testing.T) {
 	jsonData, parent, child, err := serialize()
 
@@ -254,6 +295,45 @@ func TestSerializeSkipResources(t *testing.T) {
 	assert.Equal(t, *res, Velocity{X: 1000})
 }
 
+func TestSerializeInclude(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		opts = append(opts,
+			arkserde.Opts.IncludeComponents(ecs.C[Velocity](), ecs.C[ChildOf]()),
+			arkserde.Opts.IncludeResources(ecs.C[Velocity]()),
+		)
+		jsonData, parent, child, err := serialize(opts...)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(jsonData), "Position")
+
+		// Types that are not included don't need to be registered.
+		w := ecs.NewWorld(1024)
+		_ = ecs.ComponentID[Velocity](w)
+		_ = ecs.ComponentID[ChildOf](w)
+		ecs.AddResource(w, &Velocity{})
+
+		err = arkserde.Deserialize(jsonData, w)
+		assert.Nil(t, err)
+
+		assert.True(t, w.Alive(parent))
+		assert.True(t, w.Alive(child))
+		assert.Equal(t, Velocity{X: 5, Y: 6}, *ecs.NewMap[Velocity](w).Get(child))
+		assert.Equal(t, ChildOf{Entity: parent}, *ecs.NewMap[ChildOf](w).Get(child))
+		assert.Equal(t, Velocity{X: 1000}, *ecs.GetResource[Velocity](w))
+	}
+
+	jsonData, _, _, err := serialize(
+		arkserde.Opts.IncludeComponents(ecs.C[Velocity](), ecs.C[ChildOf]()),
+		arkserde.Opts.SkipComponents(ecs.C[ChildOf]()),
+		arkserde.Opts.IncludeResources(),
+	)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(jsonData), "ChildOf")
+	assert.Contains(t, string(jsonData), "\"Resources\" : {\n}")
+}
+
 func TestSerializeRelation(t *testing.T) {
 	w := ecs.NewWorld(1024)
 	u := w.Unsafe()
@@ -340,6 +420,166 @@ func TestSerializeGeneric(t *testing.T) {
 	_, _, _ = e1, e2, e3
 }
 
+func TestSerializeDeterministic(t *testing.T) {
+	jsonData, _, _, err := serialize()
+	assert.Nil(t, err)
+
+	for range 20 {
+		jsonData2, _, _, err := serialize()
+		assert.Nil(t, err)
+		assert.Equal(t, string(jsonData), string(jsonData2))
+	}
+
+	expected := `"Types" : [
+  "arkserde_test.Position",
+  "arkserde_test.Velocity",
+  "arkserde_test.ChildOf"
+]`
+	assert.Contains(t, string(jsonData), expected)
+
+	expected = `"Resources" : {
+    "arkserde_test.Velocity" : {"X":1000,"Y":0},
+    "arkserde_test.Position" : {"X":1000,"Y":0}
+}`
+	assert.Contains(t, string(jsonData), expected)
+}
+
+func TestSerializeQualifiedNames(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	map1 := ecs.NewMap[modelOne.Position](w)
+	map2 := ecs.NewMap[modelTwo.Position](w)
+	genMap := ecs.NewMap[Generic[int32]](w)
+	genPosMap := ecs.NewMap[Generic[modelOne.Position]](w)
+
+	e1 := map1.NewEntity(&modelOne.Position{X: 1, Y: 2})
+	e2 := map2.NewEntity(&modelTwo.Position{X: 3, Y: 4})
+	e3 := genMap.NewEntity(&Generic[int32]{Value: 5})
+	e4 := genPosMap.NewEntity(&Generic[modelOne.Position]{Value: modelOne.Position{X: 6}})
+
+	_, err := arkserde.Serialize(w)
+	assert.EqualError(t, err, "component types github.com/mlange-42/ark-serde/internal/test/one/model.Position "+
+		"and github.com/mlange-42/ark-serde/internal/test/two/model.Position have the same name model.Position")
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.SkipComponents(ecs.C[modelTwo.Position]()))
+	assert.Nil(t, err)
+	// Type arguments are qualified by reflect, even without the option.
+	assert.Contains(t, string(jsonData), `"arkserde_test.Generic[github.com/mlange-42/ark-serde/internal/test/one/model.Position]" : {"Value":{"X":6,"Y":0}}`)
+
+	jsonData, err = arkserde.Serialize(w, arkserde.Opts.QualifiedNames())
+	assert.Nil(t, err)
+	fmt.Println(string(jsonData))
+
+	assert.Contains(t, string(jsonData), `"github.com/mlange-42/ark-serde/internal/test/one/model.Position" : {"X":1,"Y":2}`)
+	assert.Contains(t, string(jsonData), `"github.com/mlange-42/ark-serde/internal/test/two/model.Position" : {"X":3,"Y":4}`)
+	assert.Contains(t, string(jsonData), `"github.com/mlange-42/ark-serde_test.Generic[int32]" : {"Value":5}`)
+	assert.Contains(t, string(jsonData), `"github.com/mlange-42/ark-serde_test.Generic[github.com/mlange-42/ark-serde/internal/test/one/model.Position]" : {"Value":{"X":6,"Y":0}}`)
+
+	w = ecs.NewWorld(1024)
+	map1 = ecs.NewMap[modelOne.Position](w)
+	map2 = ecs.NewMap[modelTwo.Position](w)
+	genMap = ecs.NewMap[Generic[int32]](w)
+	genPosMap = ecs.NewMap[Generic[modelOne.Position]](w)
+
+	err = arkserde.Deserialize(jsonData, w)
+	assert.Contains(t, err.Error(), "have the same name model.Position")
+
+	err = arkserde.Deserialize(jsonData, w, arkserde.Opts.QualifiedNames())
+	assert.Nil(t, err)
+
+	assert.Equal(t, modelOne.Position{X: 1, Y: 2}, *map1.Get(e1))
+	assert.Equal(t, modelTwo.Position{X: 3, Y: 4}, *map2.Get(e2))
+	assert.Equal(t, Generic[int32]{Value: 5}, *genMap.Get(e3))
+	assert.Equal(t, Generic[modelOne.Position]{Value: modelOne.Position{X: 6}}, *genPosMap.Get(e4))
+}
+
+func TestSerializeQualifiedNamesResources(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	ecs.AddResource(w, &modelOne.Position{X: 1, Y: 2})
+	ecs.AddResource(w, &modelTwo.Position{X: 3, Y: 4})
+
+	_, err := arkserde.Serialize(w)
+	assert.Contains(t, err.Error(), "resource types")
+	assert.Contains(t, err.Error(), "have the same name model.Position")
+
+	jsonData, err := arkserde.Serialize(w, arkserde.Opts.QualifiedNames())
+	assert.Nil(t, err)
+
+	w = ecs.NewWorld(1024)
+	res1 := &modelOne.Position{}
+	res2 := &modelTwo.Position{}
+	ecs.AddResource(w, res1)
+	ecs.AddResource(w, res2)
+
+	err = arkserde.Deserialize(jsonData, w)
+	assert.Contains(t, err.Error(), "have the same name model.Position")
+
+	err = arkserde.Deserialize(jsonData, w, arkserde.Opts.QualifiedNames())
+	assert.Nil(t, err)
+
+	assert.Equal(t, modelOne.Position{X: 1, Y: 2}, *res1)
+	assert.Equal(t, modelTwo.Position{X: 3, Y: 4}, *res2)
+}
+
+func TestSerializeTo(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	mapper := ecs.NewMap1[Position](w)
+	cnt := 0
+	mapper.NewBatchFn(100, func(entity ecs.Entity, pos *Position) {
+		pos.X = float64(cnt)
+		cnt++
+	})
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	buffer := bytes.Buffer{}
+	err = arkserde.SerializeTo(&buffer, w)
+	assert.Nil(t, err)
+	assert.Equal(t, jsonData, buffer.Bytes())
+
+	buffer.Reset()
+	err = arkserde.SerializeTo(&buffer, w, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	w2 := ecs.NewWorld(1024)
+	_ = ecs.ComponentID[Position](w2)
+
+	err = arkserde.Deserialize(buffer.Bytes(), w2, arkserde.Opts.Compress())
+	assert.Nil(t, err)
+
+	filter := ecs.NewFilter1[Position](w2)
+	query := filter.Query()
+	assert.Equal(t, 100, query.Count())
+
+	cnt = 0
+	for query.Next() {
+		pos := query.Get()
+		assert.EqualValues(t, cnt, pos.X)
+		cnt++
+	}
+}
+
+type failingWriter struct{}
+
+func (w failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestSerializeToError(t *testing.T) {
+	w := ecs.NewWorld(1024)
+	mapper := ecs.NewMap2[Position, Velocity](w)
+	mapper.NewBatchFn(100, nil)
+
+	err := arkserde.SerializeTo(failingWriter{}, w)
+	assert.EqualError(t, err, "write failed")
+
+	err = arkserde.SerializeTo(failingWriter{}, w, arkserde.Opts.Compress())
+	assert.EqualError(t, err, "write failed")
+
+	err = arkserde.SerializeTo(&bytes.Buffer{}, w, arkserde.Opts.Compress(100))
+	assert.Contains(t, err.Error(), "invalid compression level")
+}
+
 func benchmarkSerializeJSON(n int, b *testing.B) {
 	w := ecs.NewWorld(1024)
 
@@ -0,0 +1,63 @@
+package arkserde
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Transient marks component and resource types that are never serialized,
+// like caches and other runtime-only data.
+// Embed it in a struct type, similar to [ecs.RelationMarker]:
+//
+//	type SpatialIndex struct {
+//		arkserde.Transient
+//		Cells map[int][]ecs.Entity
+//	}
+//
+// Transient types are left out of serialized data, as if they were skipped with
+// [Options.SkipComponents] or [Options.SkipResources] on every call.
+// In contrast to these options, they don't need to be registered for deserialization.
+// If they are registered, and found in the data, they are skipped.
+type Transient struct{}
+
+var transientType = reflect.TypeFor[Transient]()
+
+// isTransient checks whether a type is marked as transient, by an embedded [Transient].
+func isTransient(tp reflect.Type) bool {
+	if tp.Kind() != reflect.Struct {
+		return false
+	}
+	for i := range tp.NumField() {
+		if field := tp.Field(i); field.Anonymous && field.Type == transientType {
+			return true
+		}
+	}
+	return false
+}
+
+// skipsComponent checks whether a component type is skipped, by options or because it is transient.
+func (o *serdeOptions) skipsComponent(tp reflect.Type) bool {
+	return isTransient(tp) || slices.Contains(o.skipComponents, tp) ||
+		(o.includeComponents != nil && !slices.Contains(o.includeComponents, tp))
+}
+
+// skipsResource checks whether a resource type is skipped, by options or because it is transient.
+func (o *serdeOptions) skipsResource(tp reflect.Type) bool {
+	return isTransient(tp) || slices.Contains(o.skipResources, tp) ||
+		(o.includeResources != nil && !slices.Contains(o.includeResources, tp))
+}
+
+// skipMask returns a mask of the IDs of skipped component types,
+// including transient types and types not included by [Options.IncludeComponents].
+// Registers skipped types that are not registered yet.
+func skipMask(world *ecs.World, opts *serdeOptions) bitMask {
+	mask := componentMask(world, opts.skipComponents)
+	for _, id := range ecs.ComponentIDs(world) {
+		if info, ok := ecs.ComponentInfo(world, id); ok && opts.skipsComponent(info.Type) {
+			mask.Set(id, true)
+		}
+	}
+	return mask
+}
@@ -0,0 +1,118 @@
+package arkserde_test
+
+import (
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+// Cache is a transient component.
+type Cache struct {
+	arkserde.Transient
+	Values []int
+}
+
+// Lookup is a transient resource.
+type Lookup struct {
+	arkserde.Transient
+	Index map[string]ecs.Entity
+}
+
+// CachedIn is a transient relation component.
+type CachedIn struct {
+	ecs.RelationMarker
+	arkserde.Transient
+}
+
+func TestTransient(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		w := createWorld(false)
+		parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+		ecs.NewMap3[Position, Cache, CachedIn](w).NewEntity(&Position{X: 2}, &Cache{Values: []int{1, 2}}, &CachedIn{}, ecs.RelIdx(2, parent))
+		ecs.AddResource(w, &Velocity{X: 3})
+		ecs.AddResource(w, &Lookup{Index: map[string]ecs.Entity{"parent": parent}})
+
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+		assert.NotContains(t, string(jsonData), "Cache")
+		assert.NotContains(t, string(jsonData), "Lookup")
+
+		// Transient types don't need to be registered.
+		w2 := createWorld(false)
+		ecs.AddResource(w2, &Velocity{})
+		assert.Empty(t, arkserde.Validate(jsonData, w2))
+
+		err = arkserde.Deserialize(jsonData, w2)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, countEntities[Position](w2))
+		assert.Equal(t, Velocity{X: 3}, *ecs.GetResource[Velocity](w2))
+	}
+}
+
+func TestTransientBinary(t *testing.T) {
+	w := createWorld(false)
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, Cache, CachedIn](w).NewEntity(&Position{X: 2}, &Cache{Values: []int{1, 2}}, &CachedIn{}, ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 3})
+	ecs.AddResource(w, &Lookup{Index: map[string]ecs.Entity{"parent": parent}})
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 := createWorld(false)
+	ecs.AddResource(w2, &Velocity{})
+	err = arkserde.DeserializeBinary(data, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, countEntities[Position](w2))
+	assert.Equal(t, Velocity{X: 3}, *ecs.GetResource[Velocity](w2))
+}
+
+func TestTransientPrefab(t *testing.T) {
+	w := createWorld(false)
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, Cache, CachedIn](w).NewEntity(&Position{X: 2}, &Cache{Values: []int{1, 2}}, &CachedIn{}, ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 3})
+	ecs.AddResource(w, &Lookup{Index: map[string]ecs.Entity{"parent": parent}})
+
+	query := ecs.NewFilter1[Cache](w).Query()
+	query.Next()
+	child := query.Entity()
+	query.Close()
+
+	jsonData, err := arkserde.SerializeEntities(w, []ecs.Entity{child})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(jsonData), "Cache")
+
+	// The relation target is not followed, as the relation is transient.
+	w2 := createWorld(false)
+	roots, err := arkserde.Instantiate(jsonData, w2)
+	assert.Nil(t, err)
+	assert.Len(t, roots, 1)
+	assert.Equal(t, 1, countEntities[Position](w2))
+}
+
+func TestTransientInData(t *testing.T) {
+	jsonData := []byte(`{
+"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+"Types" : ["arkserde_test.Position", "arkserde_test.Cache"],
+"Components" : [
+  {"arkserde_test.Position" : {"X":1,"Y":2}, "arkserde_test.Cache" : {"Values":[1]}}
+],
+"Resources" : {"arkserde_test.Lookup" : {}}
+}`)
+
+	// Registered transient types are skipped.
+	w := createWorld(false)
+	_ = ecs.ComponentID[Cache](w)
+	ecs.AddResource(w, &Lookup{})
+	err := arkserde.Deserialize(jsonData, w)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, countEntities[Position](w))
+	assert.Equal(t, 0, countEntities[Cache](w))
+	assert.Nil(t, ecs.GetResource[Lookup](w).Index)
+}
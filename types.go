@@ -1,13 +1,75 @@
 package arkserde
 
-import "github.com/mlange-42/ark/ecs"
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mlange-42/ark/ecs"
+)
 
 const targetTag = ".ark.relation.Target"
 
+// writeBufferSize is the size of the buffer used when streaming serialized data.
+const writeBufferSize = 64 * 1024
+
+// typeName returns the name that identifies a component or resource type in serialized data.
+func typeName(tp reflect.Type, opts *serdeOptions) string {
+	if opts.qualifiedNames && tp.Name() != "" {
+		return qualifiedName(tp)
+	}
+	return tp.String()
+}
+
+// qualifiedName returns the name of a type, qualified by its full package path.
+func qualifiedName(tp reflect.Type) string {
+	if tp.Name() == "" {
+		return tp.String()
+	}
+	return tp.PkgPath() + "." + tp.Name()
+}
+
+// componentNames returns the names of all registered component types, indexed by component ID.
+func componentNames(world *ecs.World, opts *serdeOptions) []string {
+	allComps := ecs.ComponentIDs(world)
+	names := make([]string, len(allComps))
+	for _, id := range allComps {
+		if info, ok := ecs.ComponentInfo(world, id); ok {
+			names[id.Index()] = typeName(info.Type, opts)
+		}
+	}
+	return names
+}
+
+// componentMask returns a mask of the IDs of the given component types.
+// Registers types that are not registered yet.
+func componentMask(world *ecs.World, types []reflect.Type) bitMask {
+	mask := bitMask{}
+	for _, tp := range types {
+		id := ecs.TypeID(world, tp)
+		mask.Set(id, true)
+	}
+	return mask
+}
+
+// typeNames maps type names to types, for detecting name collisions.
+type typeNames map[string]reflect.Type
+
+// add a type name. Returns an error if the name is already taken by another type.
+func (n typeNames) add(name string, tp reflect.Type, kind string) error {
+	if other, ok := n[name]; ok && other != tp {
+		return fmt.Errorf("%s types %s and %s have the same name %s", kind, qualifiedName(other), qualifiedName(tp), name)
+	}
+	n[name] = tp
+	return nil
+}
+
 type deserializer struct {
+	Version    version
 	World      ecs.EntityDump
 	Types      []string
 	Components []entry
+	Archetypes []archetype
+	Roots      []int
 	Resources  map[string]entry
 }
 
@@ -20,6 +82,10 @@ func (e *entry) UnmarshalJSON(jsonData []byte) error {
 	return nil
 }
 
+func (e entry) MarshalJSON() ([]byte, error) {
+	return e.Bytes, nil
+}
+
 type component struct {
 	ID     ecs.ID
 	Comp   interface{}
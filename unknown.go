@@ -0,0 +1,230 @@
+package arkserde
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// UnknownPolicy determines how deserialization handles component and resource types
+// that are not registered in the world. See [Options.UnknownTypes].
+type UnknownPolicy uint8
+
+const (
+	// UnknownError returns an error for unknown types. This is the default.
+	UnknownError UnknownPolicy = iota
+	// UnknownIgnore skips the data of unknown types.
+	UnknownIgnore
+	// UnknownPreserve keeps the data of unknown types in an [UnknownData] resource,
+	// so that it is written back by [Serialize].
+	UnknownPreserve
+)
+
+// UnknownData holds the raw JSON of component and resource types that were not registered
+// when deserializing with [UnknownPreserve].
+//
+// It is added to the world as a resource by deserialization.
+// [Serialize] and [SerializeTo] write its content back, as if the types were registered,
+// while the resource itself is never serialized.
+// Registered types take precedence over preserved data with the same name.
+//
+// Entity references inside preserved components are written back unchanged,
+// while relation targets are remapped when merging.
+// Preserved data is not written by [SerializeBinary] and [SerializeEntities].
+type UnknownData struct {
+	Types      []string                         // Names of unknown component types.
+	Components map[ecs.Entity]map[string][]byte // Components of unknown types by entity and type name. Relation targets use the type name with suffix ".ark.relation.Target".
+	Resources  map[string][]byte                // Resources of unknown types by type name.
+}
+
+var unknownDataType = reflect.TypeFor[UnknownData]()
+
+// findUnknownData returns the [UnknownData] resource of the world, or nil if there is none.
+// In contrast to [ecs.NewResource], it does not register the resource type.
+func findUnknownData(world *ecs.World) *UnknownData {
+	for _, id := range ecs.ResourceIDs(world) {
+		if tp, ok := ecs.ResourceType(world, id); ok && tp == unknownDataType {
+			data, _ := world.Resources().Get(id).(*UnknownData)
+			return data
+		}
+	}
+	return nil
+}
+
+// preserveUnknown returns the [UnknownData] resource of the world, and adds it if there is none.
+func preserveUnknown(world *ecs.World) *UnknownData {
+	data := findUnknownData(world)
+	if data == nil {
+		data = &UnknownData{}
+		ecs.AddResource(world, data)
+	}
+	if data.Components == nil {
+		data.Components = map[ecs.Entity]map[string][]byte{}
+	}
+	if data.Resources == nil {
+		data.Resources = map[string][]byte{}
+	}
+	return data
+}
+
+// addTypes adds names to the unknown component types, if not already present.
+func (d *UnknownData) addTypes(names []string) {
+	for _, name := range names {
+		if !slices.Contains(d.Types, name) {
+			d.Types = append(d.Types, name)
+		}
+	}
+}
+
+// addComponents adds the components and relation targets of unknown types of an entity.
+// Relation targets are remapped when merging.
+func (d *UnknownData) addComponents(entity ecs.Entity, comps map[string][]byte, targets map[string]ecs.Entity, remap *entityRemap) {
+	if len(comps) == 0 && len(targets) == 0 {
+		return
+	}
+	mp, ok := d.Components[entity]
+	if !ok {
+		mp = make(map[string][]byte, len(comps)+len(targets))
+		d.Components[entity] = mp
+	}
+	for name, value := range comps {
+		mp[name] = value
+	}
+	for name, target := range targets {
+		mp[name] = entityJSON(remap.entity(target))
+	}
+}
+
+// compactJSON returns a compact copy of JSON data, for preserving it.
+func compactJSON(jsonData []byte) ([]byte, error) {
+	buffer := bytes.Buffer{}
+	if err := json.Compact(&buffer, jsonData); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// entityJSON encodes an entity. Like [ecs.Entity.MarshalJSON], it never fails.
+func entityJSON(entity ecs.Entity) []byte {
+	jsonData, _ := entity.MarshalJSON()
+	return jsonData
+}
+
+// unknownComponents provides preserved components of unknown types for serialization.
+// A nil *unknownComponents has no data.
+type unknownComponents struct {
+	data      *UnknownData
+	known     map[string]bool // Names of registered component types, which take precedence.
+	selection *entitySelection
+}
+
+// newUnknownComponents returns the preserved components of the world for serialization,
+// or nil if there are none, or components are not serialized.
+// Preserved components are not serialized with [Options.IncludeComponents].
+func newUnknownComponents(world *ecs.World, names []string, opts *serdeOptions, selection *entitySelection) *unknownComponents {
+	if opts.skipEntities || opts.skipAllComponents || opts.includeComponents != nil {
+		return nil
+	}
+	data := findUnknownData(world)
+	if data == nil || (len(data.Types) == 0 && len(data.Components) == 0) {
+		return nil
+	}
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+	return &unknownComponents{data: data, known: known, selection: selection}
+}
+
+// types returns the names of unknown component types.
+func (u *unknownComponents) types() []string {
+	if u == nil {
+		return nil
+	}
+	names := []string{}
+	for _, name := range u.data.Types {
+		if !u.known[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// keys returns the sorted names of the preserved components and relation targets of an entity.
+func (u *unknownComponents) keys(entity ecs.Entity) []string {
+	if u == nil {
+		return nil
+	}
+	comps := u.data.Components[entity]
+	if len(comps) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(comps))
+	for key := range comps {
+		if !u.known[strings.TrimSuffix(key, targetTag)] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// value returns the JSON of a preserved component or relation target of an entity.
+// Relation targets that are not serialized are replaced by the zero entity.
+func (u *unknownComponents) value(entity ecs.Entity, key string) ([]byte, error) {
+	jsonData := u.data.Components[entity][key]
+	if !strings.HasSuffix(key, targetTag) {
+		return jsonData, nil
+	}
+	var target ecs.Entity
+	if err := json.Unmarshal(jsonData, &target); err != nil {
+		return nil, err
+	}
+	return entityJSON(u.selection.target(target)), nil
+}
+
+// signature returns a string that identifies the preserved components and relation targets of an entity,
+// for grouping entities into archetypes.
+func (u *unknownComponents) signature(entity ecs.Entity) (string, error) {
+	keys := u.keys(entity)
+	if len(keys) == 0 {
+		return "", nil
+	}
+	b := strings.Builder{}
+	for _, key := range keys {
+		b.WriteString(key)
+		if strings.HasSuffix(key, targetTag) {
+			target, err := u.value(entity, key)
+			if err != nil {
+				return "", err
+			}
+			b.Write(target)
+		}
+		b.WriteByte(0)
+	}
+	return b.String(), nil
+}
+
+// unknownResources returns the preserved resources of unknown types of the world, by name,
+// without those with the names of registered resources.
+// Preserved resources are not serialized with [Options.IncludeResources].
+func unknownResources(world *ecs.World, known typeNames, opts *serdeOptions) map[string][]byte {
+	if opts.includeResources != nil {
+		return nil
+	}
+	data := findUnknownData(world)
+	if data == nil || len(data.Resources) == 0 {
+		return nil
+	}
+	resources := make(map[string][]byte, len(data.Resources))
+	for name, value := range data.Resources {
+		if _, ok := known[name]; !ok {
+			resources[name] = value
+		}
+	}
+	return resources
+}
@@ -0,0 +1,185 @@
+package arkserde_test
+
+import (
+	"bytes"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+// The types Velocity and MemberOf are those of a "plugin".
+// They are not registered by createWorld(false), which is used as the world of the host.
+func TestUnknownTypesError(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, ChildRelation, MemberOf](w).NewBatchFn(3, nil, ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 100})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	err = arkserde.Deserialize(jsonData, createWorld(false))
+	assert.EqualError(t, err, "Types: arkserde_test.Velocity: component type is not registered")
+
+	err = arkserde.Deserialize(jsonData, createWorld(false), arkserde.Opts.UnknownTypes(arkserde.UnknownError))
+	assert.EqualError(t, err, "Types: arkserde_test.Velocity: component type is not registered")
+
+	problems := arkserde.Validate(jsonData, createWorld(false))
+	assert.Len(t, problems, 3)
+}
+
+func TestUnknownTypesIgnore(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, ChildRelation, MemberOf](w).NewBatchFn(3, nil, ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 100})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := createWorld(false)
+		err = arkserde.Deserialize(jsonData, w2, arkserde.Opts.UnknownTypes(arkserde.UnknownIgnore))
+		assert.Nil(t, err)
+		assert.Equal(t, countAll(w), countAll(w2))
+		assert.Equal(t, countEntities[Position](w), countEntities[Position](w2))
+		assert.Equal(t, countEntities[ChildRelation](w), countEntities[ChildRelation](w2))
+
+		res := ecs.NewResource[arkserde.UnknownData](w2)
+		assert.False(t, res.Has())
+
+		assert.Nil(t, arkserde.Validate(jsonData, createWorld(false), arkserde.Opts.UnknownTypes(arkserde.UnknownIgnore)))
+	}
+
+	data, err := arkserde.SerializeBinary(w)
+	assert.Nil(t, err)
+
+	w2 := createWorld(false)
+	err = arkserde.DeserializeBinary(data, w2, arkserde.Opts.UnknownTypes(arkserde.UnknownIgnore))
+	assert.Nil(t, err)
+	assert.Equal(t, countEntities[Position](w), countEntities[Position](w2))
+	assert.Equal(t, countEntities[ChildRelation](w), countEntities[ChildRelation](w2))
+
+	err = arkserde.DeserializeBinary(data, createWorld(false), arkserde.Opts.UnknownTypes(arkserde.UnknownPreserve))
+	assert.EqualError(t, err, "Types: arkserde_test.Velocity: component type is not registered, and can't be preserved in the binary format")
+}
+
+func TestUnknownTypesPreserve(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, ChildRelation, MemberOf](w).NewBatchFn(3, nil, ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 100})
+
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+	} {
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		for _, load := range []func(data []byte, w *ecs.World, opts ...arkserde.Option) error{
+			arkserde.Deserialize,
+			func(data []byte, w *ecs.World, opts ...arkserde.Option) error {
+				return arkserde.DeserializeFrom(bytes.NewReader(data), w, opts...)
+			},
+		} {
+			host := createWorld(false)
+			err = load(jsonData, host, arkserde.Opts.UnknownTypes(arkserde.UnknownPreserve))
+			assert.Nil(t, err)
+
+			unknown := ecs.GetResource[arkserde.UnknownData](host)
+			assert.Equal(t, []string{"arkserde_test.Velocity", "arkserde_test.MemberOf"}, unknown.Types)
+			assert.Len(t, unknown.Components, countEntities[Velocity](w)+countEntities[MemberOf](w))
+			assert.Equal(t, map[string][]byte{
+				"arkserde_test.Velocity": []byte(`{"X":100,"Y":0}`),
+			}, unknown.Resources)
+
+			// Save the world of the host, in both layouts, and load it with all types.
+			for _, saveOpts := range [][]arkserde.Option{
+				{},
+				{arkserde.Opts.Columnar()},
+			} {
+				hostData, err := arkserde.Serialize(host, saveOpts...)
+				assert.Nil(t, err)
+				assert.NotContains(t, string(hostData), "UnknownData")
+
+				w2 := newWorldLike(w)
+				err = arkserde.Deserialize(hostData, w2)
+				assert.Nil(t, err)
+				assertEqualWorlds(t, w, w2)
+				assert.Equal(t, Velocity{X: 100}, *ecs.GetResource[Velocity](w2))
+			}
+		}
+	}
+}
+
+func TestUnknownTypesPreserveMerge(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, ChildRelation, MemberOf](w).NewBatchFn(3, nil, ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 100})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	host := createWorld(false)
+	for range 2 {
+		_, err = arkserde.Merge(jsonData, host, arkserde.Opts.UnknownTypes(arkserde.UnknownPreserve))
+		assert.Nil(t, err)
+	}
+	unknown := ecs.GetResource[arkserde.UnknownData](host)
+	assert.Len(t, unknown.Components, 2*(countEntities[Velocity](w)+countEntities[MemberOf](w)))
+
+	hostData, err := arkserde.Serialize(host)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.Deserialize(hostData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 2*countEntities[Velocity](w), countEntities[Velocity](w2))
+
+	// Relation targets of unknown types are remapped to the merged entities.
+	memberMap := ecs.NewMap[MemberOf](w2)
+	posMap := ecs.NewMap[Position](w2)
+	targets := map[float64]int{}
+	query := ecs.NewFilter1[MemberOf](w2).Query()
+	for query.Next() {
+		target := memberMap.GetRelation(query.Entity())
+		if target.IsZero() {
+			targets[0]++
+			continue
+		}
+		targets[posMap.Get(target).X]++
+	}
+	assert.Equal(t, map[float64]int{1: 2 * countEntities[MemberOf](w)}, targets)
+}
+
+func TestUnknownTypesRegisteredTakesPrecedence(t *testing.T) {
+	w := createArchetypesWorld()
+	parent := ecs.NewMap1[Position](w).NewEntity(&Position{X: 1})
+	ecs.NewMap3[Position, ChildRelation, MemberOf](w).NewBatchFn(3, nil, ecs.RelIdx(1, ecs.Entity{}), ecs.RelIdx(2, parent))
+	ecs.AddResource(w, &Velocity{X: 100})
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	host := createWorld(false)
+	err = arkserde.Deserialize(jsonData, host, arkserde.Opts.UnknownTypes(arkserde.UnknownPreserve))
+	assert.Nil(t, err)
+
+	// Velocity is registered later, e.g. by a plugin loaded after the data.
+	_ = ecs.ComponentID[Velocity](host)
+	ecs.AddResource(host, &Velocity{X: 5})
+
+	hostData, err := arkserde.Serialize(host)
+	assert.Nil(t, err)
+
+	w2 := newWorldLike(w)
+	err = arkserde.Deserialize(hostData, w2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countEntities[Velocity](w2))
+	assert.Equal(t, countEntities[MemberOf](w), countEntities[MemberOf](w2))
+	assert.Equal(t, Velocity{X: 5}, *ecs.GetResource[Velocity](w2))
+}
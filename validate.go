@@ -0,0 +1,264 @@
+package arkserde
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/goccy/go-json"
+	"github.com/mlange-42/ark/ecs"
+)
+
+// Validate checks whether data can be deserialized into the given world using [Deserialize],
+// without modifying the world.
+//
+// It performs the checks of [Deserialize], like decompression, the structure of the data,
+// the consistency of the entity pool, registered component and resource types,
+// relation targets, and decoding of each component and resource.
+// In contrast to [Deserialize], it does not stop at the first problem, but returns all problems found,
+// with at most one problem per entity or archetype.
+// Returns nil if the data is valid, i.e. if [Deserialize] would succeed.
+//
+// If world is nil, only the structure of the data is checked.
+// Types are then not resolved, and components and resources are not decoded.
+// Components are checked like those of unknown types with policy [UnknownPreserve].
+//
+// Supports the same options as [Deserialize].
+func Validate(jsonData []byte, world *ecs.World, options ...Option) []Problem {
+	opts := newSerdeOptions(options...)
+
+	v := validator{world: world, opts: &opts}
+	v.validate(jsonData)
+	return v.problems
+}
+
+// validator collects the problems of serialized data.
+type validator struct {
+	world    *ecs.World
+	opts     *serdeOptions
+	problems []Problem
+
+	schema       int
+	dump         *ecs.EntityDump // The serialized entity pool.
+	loader       *entityLoader   // Loader for checking components. Nil if it can't be created.
+	unregistered map[string]bool // Names of types reported as not registered for section Types.
+}
+
+func (v *validator) add(section string, index int, tp string, err error) {
+	p := Problem{Section: section, Index: index, Type: tp, Err: err}
+	if v.dump != nil && (section == "World" || section == "Components") {
+		p.Entity = serializedEntity(v.dump, index)
+	}
+	v.problems = append(v.problems, p)
+}
+
+// addError adds an error returned by the checks of [Deserialize].
+// Errors of types that are already reported as not registered are dropped.
+func (v *validator) addError(section string, err error) {
+	decErr := &DecodeError{}
+	if !errors.As(err, &decErr) {
+		decErr = newDecodeError(section, -1, "", err)
+	}
+	if decErr.Section != "Types" && v.unregistered[decErr.Type] {
+		return
+	}
+	v.problems = append(v.problems, *decErr)
+}
+
+func (v *validator) validate(jsonData []byte) {
+	if v.opts.compressed {
+		var err error
+		jsonData, err = uncompressGZip(jsonData)
+		if err != nil {
+			v.add("", -1, "", err)
+			return
+		}
+	}
+
+	deserial := deserializer{}
+	if err := json.Unmarshal(jsonData, &deserial); err != nil {
+		v.add("", -1, "", err)
+		return
+	}
+
+	if err := checkVersion(&deserial.Version, v.opts); err != nil {
+		v.add("Version", -1, "", err)
+	}
+	v.schema = deserial.Version.Schema
+	v.dump = &deserial.World
+
+	if !v.opts.skipEntities {
+		v.validateWorld(&deserial.World)
+		v.validateTypes(&deserial)
+		if v.loader != nil {
+			if deserial.Archetypes != nil {
+				v.validateArchetypes(&deserial)
+			} else {
+				v.validateComponents(&deserial)
+			}
+		}
+	}
+
+	if !v.opts.skipAllResources {
+		v.validateResources(deserial.Resources)
+	}
+}
+
+// validateWorld checks the entity pool, and that the world is empty.
+func (v *validator) validateWorld(dump *ecs.EntityDump) {
+	if v.world != nil {
+		if err := checkEmpty(v.world); err != nil {
+			v.add("World", -1, "", err)
+		}
+	}
+	for _, p := range checkEntities(dump) {
+		v.problems = append(v.problems, *p)
+	}
+}
+
+// validateTypes checks that the component types are registered in the world, or in the global registry,
+// and creates the loader for checking components.
+func (v *validator) validateTypes(deserial *deserializer) {
+	loader, err := v.newLoader(deserial)
+	if err != nil {
+		v.addError("Types", err)
+		return
+	}
+	v.loader = loader
+
+	v.unregistered = map[string]bool{}
+	for _, name := range deserial.Types {
+		if err := loader.checkTypes([]string{name}); err != nil {
+			v.addError("Types", err)
+			v.unregistered[name] = true
+		}
+	}
+}
+
+// newLoader creates an [entityLoader] for checking components the same way as [Deserialize].
+// Component types are registered in a separate world, so that the validated world is not modified.
+// Without a world, all types are unknown, and are checked like with policy [UnknownPreserve].
+func (v *validator) newLoader(deserial *deserializer) (*entityLoader, error) {
+	world := ecs.NewWorld()
+	opts := v.opts
+	if v.world == nil {
+		structOpts := *v.opts
+		structOpts.unknownPolicy = UnknownPreserve
+		opts = &structOpts
+	} else {
+		for _, id := range ecs.ComponentIDs(v.world) {
+			if info, ok := ecs.ComponentInfo(v.world, id); ok {
+				_ = ecs.TypeID(world, info.Type)
+			}
+		}
+		if err := registerComponents(world, deserial.Types, opts); err != nil {
+			return nil, err
+		}
+	}
+	// The world and the entity pool are checked separately, to report all problems.
+	return newEntityLoaderUnchecked(world, &deserial.World, v.schema, opts, nil)
+}
+
+// validateComponents checks the components of all entities in the default layout.
+func (v *validator) validateComponents(deserial *deserializer) {
+	if len(deserial.Components) != len(deserial.World.Alive) {
+		v.addError("Components", countError("Components", len(deserial.Components), len(deserial.World.Alive)))
+	}
+	for i, comps := range deserial.Components {
+		if _, err := v.loader.decode(i, comps.Bytes); err != nil {
+			v.addError("Components", err)
+		}
+	}
+}
+
+// validateArchetypes checks the components of all entities in the columnar layout.
+func (v *validator) validateArchetypes(deserial *deserializer) {
+	if len(deserial.Components) > 0 {
+		v.add("Archetypes", -1, "", fmt.Errorf("found both sections Components and Archetypes"))
+	}
+	for i := range deserial.Archetypes {
+		if _, err := v.loader.decodeArchetype(i, &deserial.Archetypes[i]); err != nil {
+			v.addError("Archetypes", err)
+		}
+	}
+	if err := v.loader.checkArchetypes(); err != nil {
+		v.addError("Archetypes", err)
+	}
+}
+
+// validateResources checks that the resources are present in the world, or in the global registry,
+// and decodes them.
+func (v *validator) validateResources(resources map[string]entry) {
+	if v.world == nil {
+		return
+	}
+
+	resTypes := map[string]reflect.Type{}
+	isNil := map[reflect.Type]bool{}
+	uniqueNames := typeNames{}
+	for _, id := range ecs.ResourceIDs(v.world) {
+		if tp, ok := ecs.ResourceType(v.world, id); ok {
+			name := typeName(tp, v.opts)
+			if err := uniqueNames.add(name, tp, "resource"); err != nil {
+				v.add("Resources", -1, "", err)
+				return
+			}
+			resTypes[name] = tp
+			isNil[tp] = v.world.Resources().Get(id) == nil
+		}
+	}
+	for _, alias := range v.opts.aliases {
+		if _, ok := isNil[alias.tp]; !ok {
+			continue
+		}
+		if err := uniqueNames.add(alias.name, alias.tp, "resource"); err != nil {
+			v.add("Resources", -1, "", err)
+			return
+		}
+		resTypes[alias.name] = alias.tp
+	}
+
+	registered, err := registry.byName("resource", v.opts)
+	if err != nil {
+		v.add("Resources", -1, "", err)
+		return
+	}
+
+	for _, name := range sortedKeys(resources) {
+		tp, ok := resTypes[name]
+		// Like in deserialization, resources from the registry are added if they are missing or nil.
+		fromRegistry := false
+		if regTp, isRegistered := registered[name]; isRegistered && (!ok || (isNil[tp] && regTp == tp)) {
+			tp, ok, fromRegistry = regTp, true, true
+		}
+		if !ok {
+			if v.opts.resourcePolicy(name) == UnknownError {
+				v.add("Resources", -1, name, fmt.Errorf("resource type is not registered"))
+			}
+			continue
+		}
+		if v.opts.skipsResource(tp) {
+			continue
+		}
+		if isNil[tp] && !fromRegistry {
+			v.add("Resources", -1, name, fmt.Errorf("resource type registered but nil"))
+			continue
+		}
+		if _, err := decodeComponent(tp, v.schema, resources[name].Bytes, v.opts); err != nil {
+			v.add("Resources", -1, name, err)
+		}
+	}
+}
+
+// decodeComponent migrates and decodes a single component or resource of the given type.
+func decodeComponent(tp reflect.Type, schema int, jsonData []byte, opts *serdeOptions) (reflect.Value, error) {
+	jsonData, err := migrate(tp, schema, jsonData, opts)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	value := reflect.New(tp)
+	if err := decodeValue(tp, jsonData, value.Interface(), opts); err != nil {
+		return reflect.Value{}, err
+	}
+	return value, nil
+}
@@ -0,0 +1,214 @@
+package arkserde_test
+
+import (
+	"strings"
+	"testing"
+
+	arkserde "github.com/mlange-42/ark-serde"
+	"github.com/mlange-42/ark/ecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func problemStrings(problems []arkserde.Problem) []string {
+	result := make([]string, len(problems))
+	for i, p := range problems {
+		result[i] = p.Error()
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	for _, opts := range [][]arkserde.Option{
+		{},
+		{arkserde.Opts.Columnar()},
+		{arkserde.Opts.Compress()},
+	} {
+		w := createArchetypesWorld()
+		ecs.AddResource(w, &Velocity{X: 1})
+
+		jsonData, err := arkserde.Serialize(w, opts...)
+		assert.Nil(t, err)
+
+		w2 := createWorld(true)
+		ecs.AddResource(w2, &Velocity{})
+
+		problems := arkserde.Validate(jsonData, w2, opts...)
+		assert.Empty(t, problemStrings(problems))
+
+		problems = arkserde.Validate(jsonData, nil, opts...)
+		assert.Empty(t, problemStrings(problems))
+
+		// The world is not modified.
+		assert.Equal(t, 0, countAll(w2))
+		assert.Len(t, ecs.ComponentIDs(w2), 4)
+		assert.Equal(t, Velocity{}, *ecs.GetResource[Velocity](w2))
+
+		err = arkserde.Deserialize(jsonData, w2, opts...)
+		assert.Nil(t, err)
+	}
+}
+
+func TestValidateOptions(t *testing.T) {
+	w := createArchetypesWorld()
+	ecs.AddResource(w, &Velocity{X: 1})
+
+	jsonData, err := arkserde.Serialize(w)
+	assert.Nil(t, err)
+
+	w2 := createWorld(false)
+	problems := arkserde.Validate(jsonData, w2)
+	assert.Equal(t, []string{
+		"Types: arkserde_test.Velocity: component type is not registered",
+		"Resources: arkserde_test.Velocity: resource type is not registered",
+	}, problemStrings(problems))
+
+	problems = arkserde.Validate(jsonData, w2, arkserde.Opts.SkipEntities(), arkserde.Opts.SkipAllResources())
+	assert.Empty(t, problems)
+
+	_ = ecs.ComponentID[Velocity](w2)
+	_ = ecs.ResourceID[Velocity](w2)
+	problems = arkserde.Validate(jsonData, w2,
+		arkserde.Opts.SkipComponents(ecs.C[Velocity]()),
+		arkserde.Opts.SkipResources(ecs.C[Velocity]()),
+	)
+	assert.Empty(t, problems)
+
+	problems = arkserde.Validate(jsonData, w2)
+	assert.Equal(t, []string{
+		"Resources: arkserde_test.Velocity: resource type registered but nil",
+	}, problemStrings(problems))
+
+	w.NewEntity()
+	problems = arkserde.Validate(jsonData, w, arkserde.Opts.SkipAllResources())
+	assert.Equal(t, []string{
+		"World: world must not contain any alive or dead entities",
+	}, problemStrings(problems))
+}
+
+func TestValidateErrors(t *testing.T) {
+	w := createWorld(true)
+	ecs.AddResource(w, &Velocity{})
+
+	problems := arkserde.Validate([]byte("{xxx}"), w)
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), "invalid char")
+
+	problems = arkserde.Validate([]byte("abc"), w, arkserde.Opts.Compress())
+	assert.Len(t, problems, 1)
+	assert.Contains(t, problems[0].Error(), "unexpected EOF")
+
+	problems = arkserde.Validate([]byte(textErrEntities), w)
+	assert.Equal(t, []string{
+		"Components: found components for 1 entities, but world has 2 alive entities",
+	}, problemStrings(problems))
+
+	problems = arkserde.Validate([]byte(textErrComponent2), w)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "Components", problems[0].Section)
+	assert.Equal(t, 0, problems[0].Index)
+	assert.Equal(t, "arkserde_test.Position", problems[0].Type)
+
+	problems = arkserde.Validate([]byte(textErrResource), w)
+	assert.Len(t, problems, 1)
+	assert.Equal(t, "Resources", problems[0].Section)
+	assert.Equal(t, "arkserde_test.Velocity", problems[0].Type)
+
+	problems = arkserde.Validate([]byte(textErrMultiple), w)
+	assert.Equal(t, []string{
+		"Version: data has schema version 1, but the current schema version is 0",
+		"World: entity 1: alive entity index 9 out of range for 4 entities",
+		"Types: arkserde_test.Unknown: component type is not registered",
+		"Components: found components for 3 entities, but world has 2 alive entities",
+		"Components: entity 0 {2 0}: arkserde_test.ChildRelation: relation target {3 0} is not alive",
+		"Components: entity 1: arkserde_test.Missing: component type is not registered",
+		"Components: entity 2: arkserde_test.ChildRelation: missing relation target",
+	}, problemStrings(problems))
+
+	// Without a world, only structural problems are found.
+	problems = arkserde.Validate([]byte(textErrMultiple), nil)
+	assert.Equal(t, []string{
+		"Version: data has schema version 1, but the current schema version is 0",
+		"World: entity 1: alive entity index 9 out of range for 4 entities",
+		"Components: found components for 3 entities, but world has 2 alive entities",
+	}, problemStrings(problems))
+
+	problems = arkserde.Validate([]byte(textErrEntityData), nil)
+	if assert.Len(t, problems, 1) {
+		assert.Equal(t, "Components", problems[0].Section)
+		assert.Equal(t, 0, problems[0].Index)
+	}
+}
+
+func TestValidateAlias(t *testing.T) {
+	text := strings.Replace(textAlias, `"oldpkg.Rel"`, `"oldpkg.Rel", "arkserde_test.Position"`, 1)
+	text = strings.Replace(text, `"oldpkg.Pos" : {"X":1,"Y":2}`, `"oldpkg.Pos" : {"X":1,"Y":2}, "arkserde_test.Position" : {"X":1,"Y":2}`, 1)
+	options := []arkserde.Option{
+		arkserde.Opts.Alias("oldpkg.Pos", ecs.C[Position]()),
+		arkserde.Opts.Alias("oldpkg.Rel", ecs.C[ChildRelation]()),
+		arkserde.Opts.SkipAllResources(),
+	}
+
+	w := createWorld(false)
+	problems := arkserde.Validate([]byte(text), w, options...)
+	assert.Equal(t, []string{
+		"Components: entity 0 {2 0}: oldpkg.Pos: component found more than once",
+	}, problemStrings(problems))
+
+	err := arkserde.Deserialize([]byte(text), w, options...)
+	assert.EqualError(t, err, "Components: entity 0 {2 0}: oldpkg.Pos: component found more than once")
+}
+
+func TestValidateLikeDeserialize(t *testing.T) {
+	tests := []struct {
+		text string
+		opts []arkserde.Option
+	}{
+		// Components of unknown types are ignored, even if they are not in section Types.
+		{strings.Replace(textOk, `"arkserde_test.Position" : {"X":1,"Y":2}`, `"arkserde_test.Position" : {"X":1,"Y":2}, "arkserde_test.Unknown" : {}`, 1),
+			[]arkserde.Option{arkserde.Opts.UnknownTypes(arkserde.UnknownIgnore)}},
+		// Relation targets without a component are ignored.
+		{strings.Replace(textOk, `"arkserde_test.Position" : {"X":1,"Y":2}`, `"arkserde_test.Position" : {"X":1,"Y":2}, "arkserde_test.ChildRelation.ark.relation.Target" : [3,0]`, 1),
+			nil},
+	}
+
+	for _, tt := range tests {
+		w := createWorld(true)
+		ecs.AddResource(w, &Velocity{})
+		assert.Empty(t, problemStrings(arkserde.Validate([]byte(tt.text), w, tt.opts...)))
+		assert.Nil(t, arkserde.Deserialize([]byte(tt.text), w, tt.opts...))
+	}
+}
+
+func TestValidateColumnarErrors(t *testing.T) {
+	w := createWorld(true)
+
+	jsonData, err := arkserde.Serialize(createArchetypesWorld(), arkserde.Opts.Columnar())
+	assert.Nil(t, err)
+
+	text := strings.Replace(string(jsonData), "\"Entities\" : [", "\"Entities\" : [999,", 1)
+	text = strings.Replace(text, "\"arkserde_test.Velocity\" : [{", "\"arkserde_test.Velocity\" : [{\"X\":true},{", 1)
+
+	problems := arkserde.Validate([]byte(text), w)
+	assert.Equal(t, []string{
+		"Archetypes: archetype 0: entity index 999 out of range for 31 alive entities",
+		"Archetypes: archetype 2: arkserde_test.Velocity: json: float unexpected end of JSON input",
+		"Archetypes: found components for 26 entities, but world has 31 alive entities",
+	}, problemStrings(problems))
+}
+
+const textErrEntityData = `{
+"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0]],"Alive":[2],"Next":0,"Available":0},
+"Components" : [[]]
+}`
+
+const textErrMultiple = `{
+"Version" : {"Format":1,"Schema":1},
+"World" : {"Entities":[[0,4294967295],[1,4294967295],[2,0],[3,0]],"Alive":[2,9],"Next":0,"Available":0},
+"Types" : ["arkserde_test.Position","arkserde_test.ChildRelation","arkserde_test.Position","arkserde_test.Unknown"],
+"Components" : [
+  {"arkserde_test.ChildRelation" : {}, "arkserde_test.ChildRelation.ark.relation.Target" : [3,0]},
+  {"arkserde_test.Missing" : {}},
+  {"arkserde_test.ChildRelation" : {}, "arkserde_test.Position" : {"X":true}}
+],
+"Resources" : {}
+}`